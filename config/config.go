@@ -2,47 +2,173 @@ package config
 
 import (
 	"os"
+	"reflect"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/unly/wow-addon-updater/game"
 )
 
 // Config contains the separated configurations for WoW retail and classic.
 type Config struct {
-	Classic WowConfig `yaml:"classic"`
-	Retail  WowConfig `yaml:"retail"`
+	Classic WowConfig     `yaml:"classic"`
+	Retail  WowConfig     `yaml:"retail"`
+	Sources SourcesConfig `yaml:"sources,omitempty"`
+	// optional URLs or short references of the addons other addons depend on by their folder name
+	Dependencies map[string]string `yaml:"dependencies,omitempty"`
 }
 
-// WowConfig contains the path of the interface directory where to write files to.
+// Compatibility is the handling of addons not supporting the game interface version of an installation.
+type Compatibility string
+
+const (
+	// Warn reports incompatible addons in the summary but installs them
+	Warn Compatibility = "warn"
+	// Refuse reports incompatible addons in the summary and keeps their installed version
+	Refuse Compatibility = "refuse"
+	// Ignore skips the compatibility check, the default
+	Ignore Compatibility = "ignore"
+)
+
+// WowConfig contains the path of the AddOns directory where to write files to.
 // The list of addons should be a list of supported URLs.
 type WowConfig struct {
-	// path to the respective interface directory of the installation
+	// path to the AddOns directory of the installation, its Interface directory or the game directory
 	Path string `yaml:"path"`
-	// list of addon URLs to update
-	AddOns []string `yaml:"addons"`
+	// optional flavor of the installation. Defaults to the flavor of the section
+	Flavor game.Flavor `yaml:"flavor,omitempty"`
+	// optional game interface version of the installation, e.g. 90100. Detected from its .build.info file if not set
+	Interface int `yaml:"interface,omitempty"`
+	// optional handling of addons not supporting the interface version. Defaults to ignore
+	Compatibility Compatibility `yaml:"compatibility,omitempty"`
+	// list of addons to update
+	AddOns []AddOn `yaml:"addons"`
+}
+
+// AddOn is an addon to update. In YAML it is either the plain URL
+// or a mapping of the URL and additional options.
+type AddOn struct {
+	// URL of the addon
+	URL string `yaml:"url"`
+	// optional name of the source to use instead of the first one matching the URL
+	Source string `yaml:"source,omitempty"`
+	// optional names of the files of the addon to install for sources offering several files
+	Files []string `yaml:"files,omitempty"`
+	// optional flag to keep the installed version of the addon instead of updating it
+	Pinned bool `yaml:"pinned,omitempty"`
+}
+
+// UnmarshalYAML reads in the addon from a plain URL or a mapping.
+func (a *AddOn) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*a = AddOn{URL: value.Value}
+		return nil
+	}
+
+	type plain AddOn
+	return value.Decode((*plain)(a))
+}
+
+// MarshalYAML writes the addon as plain URL if there are no additional options.
+func (a AddOn) MarshalYAML() (interface{}, error) {
+	if a.Source == "" && len(a.Files) == 0 && !a.Pinned {
+		return a.URL, nil
+	}
+
+	type plain AddOn
+	return plain(a), nil
+}
+
+// SourcesConfig contains the settings of the addon sources.
+type SourcesConfig struct {
+	// names of the sources to look up first in the given order
+	Order []string `yaml:"order,omitempty"`
+	// names of the sources not to use
+	Disabled []string `yaml:"disabled,omitempty"`
+	// self-hosted GitLab instances and access tokens
+	GitLab []HostConfig `yaml:"gitlab,omitempty"`
+	// self-hosted Gitea and Forgejo instances and access tokens
+	Gitea []HostConfig `yaml:"gitea,omitempty"`
+	// user-defined sources scraping addon pages
+	Scrapers []ScraperConfig `yaml:"scrapers,omitempty"`
+	// external executables implementing a source
+	Plugins []PluginConfig `yaml:"plugins,omitempty"`
+}
+
+// HostConfig contains the base URL of a self-hosted instance of a source
+// and an optional access token for private projects.
+type HostConfig struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token,omitempty"`
+}
+
+// ScraperConfig defines a source reading the version and download link of an addon
+// from its HTML page.
+type ScraperConfig struct {
+	// name of the source
+	Name string `yaml:"name"`
+	// regular expression matching the addon URLs. Named groups can be used in the download template
+	URL string `yaml:"url"`
+	// CSS selector of the element containing the version
+	Version string `yaml:"version"`
+	// optional regular expression with a group extracting the version from the element text
+	VersionPattern string `yaml:"version_pattern,omitempty"`
+	// CSS selector of the download link
+	Download string `yaml:"download,omitempty"`
+	// template of the download URL as alternative to the download selector
+	DownloadTemplate string `yaml:"download_template,omitempty"`
+}
+
+// PluginConfig defines an external executable implementing a source
+// over a JSON protocol on stdin and stdout.
+type PluginConfig struct {
+	// name of the source
+	Name string `yaml:"name"`
+	// path of the executable
+	Command string `yaml:"command"`
+	// optional arguments passed to the executable
+	Args []string `yaml:"args,omitempty"`
 }
 
 // ReadConfig reads in the configuration from the given path.
 // The content is expected to be YAML.
-// Returns an error if not existing.
+// Returns an error if not existing and a ValidationError with the positions of
+// unknown keys and invalid values.
 func ReadConfig(path string) (Config, error) {
 	var c Config
-	in, err := os.ReadFile(path)
+	root, err := readNode(path)
 	if err != nil {
 		return c, err
 	}
+	if root.Kind == 0 {
+		return c, nil
+	}
 
-	err = yaml.Unmarshal(in, &c)
+	problems := checkNode(root, reflect.TypeOf(c), "config")
+	if len(problems) > 0 {
+		return c, &ValidationError{Path: path, Problems: problems}
+	}
+
+	err = root.Decode(&c)
 
 	return c, err
 }
 
-// CreateDefaultConfig writes an empty config in YAML to the given path.
-func CreateDefaultConfig(path string) error {
-	var c Config
-	out, err := yaml.Marshal(c)
-	if err != nil {
-		return err
-	}
+// defaultConfig is the empty config with comments explaining its keys
+const defaultConfig = `# see https://github.com/unly/wow-addon-updater for all options
+classic:
+    # path to the AddOns directory of the classic installation or the game directory
+    path: ""
+    # addon URLs or short references, e.g. wowi:24608
+    addons: []
+retail:
+    # path to the AddOns directory of the retail installation or the game directory
+    path: ""
+    # addon URLs or short references, e.g. gh:owner/repo
+    addons: []
+`
 
-	return os.WriteFile(path, out, os.FileMode(0666))
+// CreateDefaultConfig writes an empty config in YAML with comments to the given path.
+func CreateDefaultConfig(path string) error {
+	return os.WriteFile(path, []byte(defaultConfig), os.FileMode(0666))
 }
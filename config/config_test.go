@@ -6,7 +6,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 
+	"github.com/unly/wow-addon-updater/game"
 	"github.com/unly/wow-addon-updater/util/tests/helpers"
 )
 
@@ -42,14 +44,28 @@ func TestReadConfig(t *testing.T) {
 		content := []byte(`
 classic:
   path: path/to/classic
+  flavor: classic-tbc
   addons:
     - addon1
     - addon2
 retail:
   path: path/to/retail
+  interface: 90100
+  compatibility: refuse
   addons:
     - addon3
-    - addon4`)
+    - url: addon4
+      source: github
+sources:
+  order:
+    - github
+  disabled:
+    - direct
+  gitlab:
+    - url: https://gitlab.example.com
+      token: secret
+dependencies:
+  LibStub: https://example.com/libstub.zip`)
 		file := helpers.TempFile(t, "", content)
 		defer helpers.DeleteFile(t, file)
 
@@ -58,22 +74,108 @@ retail:
 		assert.NoError(t, err)
 		want := Config{
 			Classic: WowConfig{
-				Path: "path/to/classic",
-				AddOns: []string{
-					"addon1",
-					"addon2",
+				Path:   "path/to/classic",
+				Flavor: game.ClassicTBC,
+				AddOns: []AddOn{
+					{URL: "addon1"},
+					{URL: "addon2"},
 				},
 			},
 			Retail: WowConfig{
-				Path: "path/to/retail",
-				AddOns: []string{
-					"addon3",
-					"addon4",
+				Path:          "path/to/retail",
+				Interface:     90100,
+				Compatibility: Refuse,
+				AddOns: []AddOn{
+					{URL: "addon3"},
+					{URL: "addon4", Source: "github"},
 				},
 			},
+			Sources: SourcesConfig{
+				Order:    []string{"github"},
+				Disabled: []string{"direct"},
+				GitLab: []HostConfig{
+					{
+						URL:   "https://gitlab.example.com",
+						Token: "secret",
+					},
+				},
+			},
+			Dependencies: map[string]string{
+				"LibStub": "https://example.com/libstub.zip",
+			},
 		}
 		assert.Equal(t, want, cfg)
 	})
+	t.Run("unknown keys", func(t *testing.T) {
+		content := []byte(`
+retail:
+  path: path/to/retail
+  adons:
+    - addon1
+classic:
+  flavor: tbc
+  addons:
+    - url: addon2
+      file: main`)
+		file := helpers.TempFile(t, "", content)
+		defer helpers.DeleteFile(t, file)
+
+		_, err := ReadConfig(file)
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, file, validationErr.Path)
+		assert.Len(t, validationErr.Problems, 3)
+		assert.Equal(t, Problem{Line: 4, Column: 3, Message: "unknown key adons in retail, expected one of addons, compatibility, flavor, interface, path"},
+			validationErr.Problems[0])
+		assert.Equal(t, 7, validationErr.Problems[1].Line)
+		assert.Equal(t, 10, validationErr.Problems[2].Line)
+		assert.Equal(t, 7, validationErr.Problems[2].Column)
+	})
+}
+
+func TestAddOn_MarshalYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		addon AddOn
+		want  string
+	}{
+		{
+			name:  "plain url",
+			addon: AddOn{URL: "addon1"},
+			want:  "addon1\n",
+		},
+		{
+			name:  "with source",
+			addon: AddOn{URL: "addon1", Source: "github"},
+			want:  "url: addon1\nsource: github\n",
+		},
+		{
+			name:  "with files",
+			addon: AddOn{URL: "addon1", Files: []string{"main", "classic"}},
+			want:  "url: addon1\nfiles:\n    - main\n    - classic\n",
+		},
+		{
+			name:  "pinned",
+			addon: AddOn{URL: "addon1", Pinned: true},
+			want:  "url: addon1\npinned: true\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := yaml.Marshal(tt.addon)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(out))
+
+			var got AddOn
+			err = yaml.Unmarshal(out, &got)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.addon, got)
+		})
+	}
 }
 
 func TestCreateDefaultConfig(t *testing.T) {
@@ -86,6 +188,9 @@ func TestCreateDefaultConfig(t *testing.T) {
 		err := CreateDefaultConfig(file)
 
 		assert.NoError(t, err)
+		c, err := ReadConfig(file)
+		assert.NoError(t, err)
+		assert.Equal(t, Config{Classic: WowConfig{AddOns: []AddOn{}}, Retail: WowConfig{AddOns: []AddOn{}}}, c)
 		helpers.DeleteDir(t, file)
 	})
 	t.Run("existing read only file", func(t *testing.T) {
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/unly/wow-addon-updater/game"
+)
+
+// default indentation of the YAML encoder
+const defaultIndent = 4
+
+// Editor changes a config file on its YAML nodes to keep the comments,
+// the order of the keys and the quoting of the values of the file.
+// The lines of the entries left unchanged are written as they are.
+type Editor struct {
+	doc    *yaml.Node
+	indent int
+	// original lines of the file or nil for a new one
+	layout *layout
+}
+
+// ReadEditor returns an Editor for the config file of the given path.
+// A missing file is edited as an empty config.
+func ReadEditor(path string) (*Editor, error) {
+	in, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return NewEditor(in)
+}
+
+// NewEditor returns an Editor for the given YAML content of a config file.
+// Returns an error if the content is no YAML mapping.
+func NewEditor(in []byte) (*Editor, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(in, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the config is no mapping in line %d", root.Line)
+	}
+
+	indent := detectIndent(root)
+
+	return &Editor{
+		doc:    &doc,
+		indent: indent,
+		layout: newLayout(in, &doc, indent),
+	}, nil
+}
+
+// AddAddOn appends the addon to the addons of the section, i.e. retail or classic.
+// Returns false if the section already contains the addon URL.
+func (e *Editor) AddAddOn(section string, addon AddOn) (bool, error) {
+	node, err := e.section(section)
+	if err != nil {
+		return false, err
+	}
+
+	addons := mappingValue(node, "addons")
+	if addons == nil || addons.Kind != yaml.SequenceNode {
+		addons = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(node, "addons", addons)
+	}
+	for _, item := range addons.Content {
+		if addonURL(item) == addon.URL {
+			return false, nil
+		}
+	}
+
+	var item yaml.Node
+	err = item.Encode(addon)
+	if err != nil {
+		return false, err
+	}
+	if len(addons.Content) == 0 {
+		addons.Style = 0
+	}
+	addons.Content = append(addons.Content, &item)
+
+	return true, nil
+}
+
+// RemoveAddOn removes the addon of the given URL from all sections. The comment above
+// a removed entry is moved to the next one. Returns the number of removed entries.
+func (e *Editor) RemoveAddOn(url string) int {
+	removed := 0
+	for _, addons := range e.addonLists() {
+		content := make([]*yaml.Node, 0, len(addons.Content))
+		comment := ""
+		for _, item := range addons.Content {
+			if addonURL(item) == url {
+				removed++
+				if comment == "" {
+					comment = item.HeadComment
+				}
+				continue
+			}
+			if item.HeadComment == "" {
+				item.HeadComment = comment
+			}
+			comment = ""
+			content = append(content, item)
+		}
+		addons.Content = content
+	}
+
+	return removed
+}
+
+// PinAddOn sets the pinned option of the addon of the given URL in all sections.
+// A plain URL becomes a mapping when pinned and the other way around when unpinned
+// without further options. Returns the number of changed entries.
+func (e *Editor) PinAddOn(url string, pinned bool) int {
+	changed := 0
+	for _, addons := range e.addonLists() {
+		for _, item := range addons.Content {
+			if addonURL(item) != url {
+				continue
+			}
+
+			// the entries are changed in place to keep their position in the original lines
+			switch {
+			case pinned && item.Kind == yaml.ScalarNode:
+				*item = yaml.Node{
+					Kind:        yaml.MappingNode,
+					Tag:         "!!map",
+					HeadComment: item.HeadComment,
+					FootComment: item.FootComment,
+					Content: []*yaml.Node{
+						{Kind: yaml.ScalarNode, Tag: "!!str", Value: "url"},
+						{Kind: yaml.ScalarNode, Tag: "!!str", Value: item.Value, Style: item.Style, LineComment: item.LineComment},
+						{Kind: yaml.ScalarNode, Tag: "!!str", Value: "pinned"},
+						{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"},
+					},
+				}
+			case pinned:
+				setMappingValue(item, "pinned", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+			case item.Kind == yaml.MappingNode:
+				if !deleteMappingKey(item, "pinned") {
+					continue
+				}
+				if len(item.Content) == 2 && item.Content[0].Value == "url" {
+					value := *item.Content[1]
+					value.HeadComment = item.HeadComment
+					value.FootComment = item.FootComment
+					*item = value
+				}
+			default:
+				continue
+			}
+			changed++
+		}
+	}
+
+	return changed
+}
+
+// SetInstallation sets the path and the optional flavor of the section, i.e. retail or classic.
+// Values equal to the current ones are kept as they are.
+func (e *Editor) SetInstallation(section, path string, flavor game.Flavor) error {
+	node, err := e.section(section)
+	if err != nil {
+		return err
+	}
+
+	if mappingValue(node, "path") == nil {
+		insertMappingValue(node, 0, "path")
+	}
+	setMappingValue(node, "path", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path})
+
+	if flavor == "" {
+		return nil
+	}
+	if mappingValue(node, "flavor") == nil {
+		insertMappingValue(node, mappingIndex(node, "path")+1, "flavor")
+	}
+	setMappingValue(node, "flavor", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(flavor)})
+
+	return nil
+}
+
+// Bytes returns the YAML content of the edited config. Only the changed mapping pairs
+// and sequence items are formatted anew, the other lines are kept as they are.
+func (e *Editor) Bytes() ([]byte, error) {
+	if e.layout != nil {
+		return e.layout.render(e.doc, e.indent)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(e.indent)
+	err := enc.Encode(e.doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Write writes the edited config to the given path.
+func (e *Editor) Write(path string) error {
+	out, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out, os.FileMode(0666))
+}
+
+// section returns the mapping of the retail or classic section. A missing or empty section is added.
+func (e *Editor) section(name string) (*yaml.Node, error) {
+	if name != "retail" && name != "classic" {
+		return nil, fmt.Errorf("unknown section %s, expected retail or classic", name)
+	}
+
+	root := documentContent(e.doc)
+	node := mappingValue(root, name)
+	if node != nil && node.Kind == yaml.MappingNode {
+		return node, nil
+	}
+
+	node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingValue(root, name, node)
+
+	return node, nil
+}
+
+// addonLists returns the addon sequences of all sections
+func (e *Editor) addonLists() []*yaml.Node {
+	lists := make([]*yaml.Node, 0, 2)
+	for _, name := range []string{"retail", "classic"} {
+		addons := mappingValue(mappingValue(documentContent(e.doc), name), "addons")
+		if addons != nil && addons.Kind == yaml.SequenceNode {
+			lists = append(lists, addons)
+		}
+	}
+
+	return lists
+}
+
+// addonURL returns the URL of an addon entry, either the plain URL or the url key of its mapping
+func addonURL(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+
+	if url := mappingValue(node, "url"); url != nil {
+		return url.Value
+	}
+
+	return ""
+}
+
+// setMappingValue replaces the value of the key of a mapping node or appends the key and the value.
+// The comments of a replaced value are kept as well as the value if it did not change.
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+
+		old := node.Content[i+1]
+		if old.Kind == value.Kind && old.Kind == yaml.ScalarNode && old.Value == value.Value {
+			return
+		}
+		value.HeadComment = old.HeadComment
+		value.LineComment = old.LineComment
+		value.FootComment = old.FootComment
+		node.Content[i+1] = value
+		return
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// mappingIndex returns the index of the key value pair of the key in a mapping node or -1 if there is none
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i / 2
+		}
+	}
+
+	return -1
+}
+
+// insertMappingValue inserts the key with a null value at the index of the key value pairs of a mapping node
+func insertMappingValue(node *yaml.Node, index int, key string) {
+	i := 2 * index
+	if i > len(node.Content) {
+		i = len(node.Content)
+	}
+
+	pair := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		{Kind: yaml.ScalarNode, Tag: "!!null"},
+	}
+	node.Content = append(node.Content[:i], append(pair, node.Content[i:]...)...)
+}
+
+// deleteMappingKey removes the key and its value from a mapping node. Returns false if there is no such key.
+func deleteMappingKey(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// detectIndent returns the indentation of the nested mappings of the root or the default one
+func detectIndent(root *yaml.Node) int {
+	for i := 1; i < len(root.Content); i += 2 {
+		value := root.Content[i]
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 && value.Style&yaml.FlowStyle == 0 {
+			if indent := value.Content[0].Column - root.Column; indent > 0 {
+				return indent
+			}
+		}
+	}
+
+	return defaultIndent
+}
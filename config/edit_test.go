@@ -0,0 +1,298 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+const editedConfig = `# my addons
+retail:
+  # game directory
+  path: "C:/Games/World of Warcraft"
+  addons:
+    - gh:owner/repo # main addon
+    # libraries
+    - wowi:24608
+    - url: tukui:elvui
+      source: tukui
+classic:
+  addons: []
+`
+
+// handWrittenConfig is formatted unlike the YAML encoder with blank lines between the sections,
+// sequences at the indentation of their key and two spaces before comments
+const handWrittenConfig = `---
+# my addons
+
+retail:
+    path: C:/Games/World of Warcraft/_retail_/Interface/AddOns  # game directory
+    addons:
+    - gh:owner/repo  # main addon
+
+    # libraries
+    - 'wowi:24608'
+    - url: tukui:elvui
+      source: tukui
+
+classic:
+    path: C:/Games/World of Warcraft/_classic_/Interface/AddOns
+    addons: []
+
+sources:
+    disabled: [tukui]  # not needed
+
+# end of file
+`
+
+func TestNewEditor(t *testing.T) {
+	t.Run("invalid content", func(t *testing.T) {
+		_, err := NewEditor([]byte("hello world"))
+
+		assert.Error(t, err)
+	})
+	t.Run("unchanged", func(t *testing.T) {
+		editor, err := NewEditor([]byte(editedConfig))
+		assert.NoError(t, err)
+
+		out, err := editor.Bytes()
+
+		assert.NoError(t, err)
+		assert.Equal(t, editedConfig, string(out))
+	})
+	t.Run("unchanged hand-written", func(t *testing.T) {
+		editor, err := NewEditor([]byte(handWrittenConfig))
+		assert.NoError(t, err)
+
+		out, err := editor.Bytes()
+
+		assert.NoError(t, err)
+		assert.Equal(t, handWrittenConfig, string(out))
+	})
+	t.Run("windows line endings", func(t *testing.T) {
+		in := strings.ReplaceAll(editedConfig, "\n", "\r\n")
+		editor, err := NewEditor([]byte(in))
+		assert.NoError(t, err)
+
+		_, err = editor.AddAddOn("classic", AddOn{URL: "wowi:1"})
+		assert.NoError(t, err)
+		out, err := editor.Bytes()
+
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Replace(in, "  addons: []\r\n", "  addons:\r\n    - wowi:1\r\n", 1), string(out))
+	})
+	t.Run("empty content", func(t *testing.T) {
+		editor, err := NewEditor(nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, editor.SetInstallation("retail", "path/to/retail", ""))
+		out, err := editor.Bytes()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "retail:\n    path: path/to/retail\n", string(out))
+	})
+}
+
+func TestEditor_AddAddOn(t *testing.T) {
+	editor, err := NewEditor([]byte(editedConfig))
+	assert.NoError(t, err)
+
+	added, err := editor.AddAddOn("retail", AddOn{URL: "example.com/addon.zip"})
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = editor.AddAddOn("retail", AddOn{URL: "tukui:elvui"})
+	assert.NoError(t, err)
+	assert.False(t, added)
+	added, err = editor.AddAddOn("classic", AddOn{URL: "wowi:1", Files: []string{"main"}})
+	assert.NoError(t, err)
+	assert.True(t, added)
+	_, err = editor.AddAddOn("tbc", AddOn{URL: "wowi:1"})
+	assert.Error(t, err)
+
+	out, err := editor.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, `# my addons
+retail:
+  # game directory
+  path: "C:/Games/World of Warcraft"
+  addons:
+    - gh:owner/repo # main addon
+    # libraries
+    - wowi:24608
+    - url: tukui:elvui
+      source: tukui
+    - example.com/addon.zip
+classic:
+  addons:
+    - url: wowi:1
+      files:
+        - main
+`, string(out))
+}
+
+func TestEditor_RemoveAddOn(t *testing.T) {
+	editor, err := NewEditor([]byte(editedConfig))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, editor.RemoveAddOn("wowi:24608"))
+	assert.Equal(t, 0, editor.RemoveAddOn("wowi:1"))
+
+	out, err := editor.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, `# my addons
+retail:
+  # game directory
+  path: "C:/Games/World of Warcraft"
+  addons:
+    - gh:owner/repo # main addon
+    # libraries
+    - url: tukui:elvui
+      source: tukui
+classic:
+  addons: []
+`, string(out))
+}
+
+func TestEditor_PinAddOn(t *testing.T) {
+	editor, err := NewEditor([]byte(editedConfig))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, editor.PinAddOn("gh:owner/repo", true))
+	assert.Equal(t, 1, editor.PinAddOn("tukui:elvui", true))
+	assert.Equal(t, 0, editor.PinAddOn("wowi:24608", false))
+
+	out, err := editor.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, `# my addons
+retail:
+  # game directory
+  path: "C:/Games/World of Warcraft"
+  addons:
+    - url: gh:owner/repo # main addon
+      pinned: true
+    # libraries
+    - wowi:24608
+    - url: tukui:elvui
+      source: tukui
+      pinned: true
+classic:
+  addons: []
+`, string(out))
+
+	assert.Equal(t, 1, editor.PinAddOn("gh:owner/repo", false))
+	assert.Equal(t, 1, editor.PinAddOn("tukui:elvui", false))
+	out, err = editor.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, editedConfig, string(out))
+}
+
+func TestEditor_SetInstallation(t *testing.T) {
+	editor, err := NewEditor([]byte(editedConfig))
+	assert.NoError(t, err)
+
+	assert.NoError(t, editor.SetInstallation("retail", "C:/Games/World of Warcraft", ""))
+	assert.NoError(t, editor.SetInstallation("classic", "path/to/classic", game.ClassicTBC))
+	assert.Error(t, editor.SetInstallation("tbc", "path/to/classic", ""))
+
+	out, err := editor.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, `# my addons
+retail:
+  # game directory
+  path: "C:/Games/World of Warcraft"
+  addons:
+    - gh:owner/repo # main addon
+    # libraries
+    - wowi:24608
+    - url: tukui:elvui
+      source: tukui
+classic:
+  path: path/to/classic
+  flavor: classic-tbc
+  addons: []
+`, string(out))
+}
+
+func TestEditor_Write(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	file := filepath.Join(dir, "config.yaml")
+
+	editor, err := ReadEditor(file)
+	assert.NoError(t, err)
+	_, err = editor.AddAddOn("retail", AddOn{URL: "wowi:24608", Pinned: true})
+	assert.NoError(t, err)
+
+	assert.NoError(t, editor.Write(file))
+	c, err := ReadConfig(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []AddOn{{URL: "wowi:24608", Pinned: true}}, c.Retail.AddOns)
+	assert.Error(t, editor.Write(filepath.Join(dir, "missing", "config.yaml")))
+}
+
+func TestEditor_HandWritten(t *testing.T) {
+	editor, err := NewEditor([]byte(handWrittenConfig))
+	assert.NoError(t, err)
+
+	_, err = editor.AddAddOn("retail", AddOn{URL: "example.com/addon.zip"})
+	assert.NoError(t, err)
+	_, err = editor.AddAddOn("classic", AddOn{URL: "wowi:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, editor.RemoveAddOn("gh:owner/repo"))
+	assert.Equal(t, 1, editor.PinAddOn("wowi:24608", true))
+	assert.NoError(t, editor.SetInstallation("classic", "path/to/classic", game.ClassicTBC))
+	_, err = editor.AddAddOn("retail", AddOn{URL: "wowi:2"})
+	assert.NoError(t, err)
+
+	out, err := editor.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, `---
+# my addons
+
+retail:
+    path: C:/Games/World of Warcraft/_retail_/Interface/AddOns  # game directory
+    addons:
+    # libraries
+    - url: 'wowi:24608'
+      pinned: true
+    - url: tukui:elvui
+      source: tukui
+    - example.com/addon.zip
+    - wowi:2
+
+classic:
+    path: path/to/classic
+    flavor: classic-tbc
+    addons:
+    - wowi:1
+
+sources:
+    disabled: [tukui]  # not needed
+
+# end of file
+`, string(out))
+
+	assert.Equal(t, 1, editor.PinAddOn("wowi:24608", false))
+	assert.Equal(t, 1, editor.RemoveAddOn("example.com/addon.zip"))
+	assert.Equal(t, 1, editor.RemoveAddOn("wowi:2"))
+	out, err = editor.Bytes()
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "    # libraries\n    - 'wowi:24608'\n    - url: tukui:elvui\n      source: tukui\n\nclassic:\n")
+}
+
+func TestEditor_NewSection(t *testing.T) {
+	editor, err := NewEditor([]byte("retail:\n  path: path/to/retail\n\nsources:\n  order: [direct]\n"))
+	assert.NoError(t, err)
+
+	_, err = editor.AddAddOn("classic", AddOn{URL: "wowi:1"})
+	assert.NoError(t, err)
+	out, err := editor.Bytes()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "retail:\n  path: path/to/retail\n\nsources:\n  order: [direct]\n\nclassic:\n  addons:\n    - wowi:1\n", string(out))
+}
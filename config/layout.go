@@ -0,0 +1,375 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// layout keeps the lines of the original content of an edited config. Unchanged mapping pairs
+// and sequence items are written as they are, so only the edited ones lose their formatting.
+type layout struct {
+	lines []string
+	// line spans of the entries of the block mappings and sequences by their node
+	entries map[*yaml.Node][]entry
+	// original state of every node of the document
+	nodes map[*yaml.Node]snapshot
+	// indentation of the sequence items relative to their key
+	seqIndent int
+	newline   string
+}
+
+// entry is the line span of a mapping pair or a sequence item in the original content
+type entry struct {
+	// key of the pair or the item itself
+	node *yaml.Node
+	// original value of the pair
+	value *yaml.Node
+	// first line including the comment lines right above the entry
+	start int
+	// line of the key or the item
+	line int
+	// line after the content of the entry
+	end int
+	// start of the next entry or the end of the container. Blank and comment
+	// lines between end and next are kept after the entry.
+	next int
+}
+
+type snapshot struct {
+	kind    yaml.Kind
+	style   yaml.Style
+	tag     string
+	value   string
+	content []*yaml.Node
+}
+
+// newLayout returns the layout of the content parsed to the document or nil if there is no block mapping to keep
+func newLayout(in []byte, doc *yaml.Node, indent int) *layout {
+	root := documentContent(doc)
+	if len(in) == 0 || root == nil || len(root.Content) == 0 || root.Style&yaml.FlowStyle != 0 {
+		return nil
+	}
+
+	text := string(in)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	l := &layout{
+		lines:     strings.SplitAfter(text, "\n"),
+		entries:   make(map[*yaml.Node][]entry),
+		nodes:     make(map[*yaml.Node]snapshot),
+		seqIndent: -1,
+		newline:   "\n",
+	}
+	l.lines = l.lines[:len(l.lines)-1]
+	if strings.HasSuffix(l.lines[0], "\r\n") {
+		l.newline = "\r\n"
+	}
+
+	l.snapshot(doc)
+	l.addEntries(root, -1, l.contentEnd(-1, len(l.lines)))
+	if l.seqIndent < 0 {
+		l.seqIndent = indent
+	}
+
+	return l
+}
+
+func (l *layout) snapshot(node *yaml.Node) {
+	l.nodes[node] = snapshot{
+		kind:    node.Kind,
+		style:   node.Style,
+		tag:     node.Tag,
+		value:   node.Value,
+		content: append([]*yaml.Node{}, node.Content...),
+	}
+	for _, child := range node.Content {
+		l.snapshot(child)
+	}
+}
+
+// addEntries adds the line spans of the entries of a block mapping or sequence starting below
+// the header line and ending before the bound. The entries of nested block mappings and sequences
+// are added as well, whereas the items of a sequence are kept as a whole.
+func (l *layout) addEntries(node *yaml.Node, header, bound int) {
+	if node.Style&yaml.FlowStyle != 0 || (node.Kind != yaml.MappingNode && node.Kind != yaml.SequenceNode) {
+		return
+	}
+
+	entries := make([]entry, 0, len(node.Content))
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			entries = append(entries, entry{node: node.Content[i], value: node.Content[i+1], line: node.Content[i].Line - 1})
+		}
+	} else {
+		for _, item := range node.Content {
+			entries = append(entries, entry{node: item, line: item.Line - 1})
+		}
+	}
+	if len(entries) == 0 || entries[0].line <= header {
+		return
+	}
+
+	for i := range entries {
+		next := bound
+		if i+1 < len(entries) {
+			next = entries[i+1].line
+		}
+		entries[i].end = l.contentEnd(entries[i].line, next)
+
+		lower := header + 1
+		if i > 0 {
+			lower = entries[i-1].end
+		}
+		entries[i].start = entries[i].line
+		for entries[i].start > lower && isComment(l.lines[entries[i].start-1]) {
+			entries[i].start--
+		}
+		if i > 0 {
+			entries[i-1].next = entries[i].start
+		}
+	}
+	entries[len(entries)-1].next = entries[len(entries)-1].end
+	l.entries[node] = entries
+
+	if node.Kind == yaml.SequenceNode {
+		return
+	}
+	for _, e := range entries {
+		l.addEntries(e.value, e.line, e.end)
+		if items, ok := l.entries[e.value]; ok && e.value.Kind == yaml.SequenceNode && l.seqIndent < 0 {
+			l.seqIndent = indentation(l.lines[items[0].line]) - (e.node.Column - 1)
+		}
+	}
+}
+
+// contentEnd returns the line after the last line before the bound which is neither blank nor a comment
+func (l *layout) contentEnd(line, bound int) int {
+	end := bound
+	for end-1 > line && (isBlank(l.lines[end-1]) || isComment(l.lines[end-1])) {
+		end--
+	}
+
+	return end
+}
+
+// unchanged returns true if the node and all its children are in their original state
+func (l *layout) unchanged(node *yaml.Node) bool {
+	s, ok := l.nodes[node]
+	if !ok || s.kind != node.Kind || s.style != node.Style || s.tag != node.Tag || s.value != node.Value || len(s.content) != len(node.Content) {
+		return false
+	}
+
+	for i, child := range node.Content {
+		if child != s.content[i] || !l.unchanged(child) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// render returns the content of the document with the original lines of the unchanged entries
+func (l *layout) render(doc *yaml.Node, indent int) ([]byte, error) {
+	root := documentContent(doc)
+	entries := l.entries[root]
+	last := entries[len(entries)-1]
+
+	var buf bytes.Buffer
+	l.write(&buf, 0, entries[0].start)
+	err := l.renderEntries(&buf, root, indent)
+	if err != nil {
+		return nil, err
+	}
+	l.write(&buf, last.end, len(l.lines))
+
+	return buf.Bytes(), nil
+}
+
+// renderEntries writes the entries of the container. The comment lines above a removed entry are
+// moved to the next one without comment. New pairs of a mapping follow a blank line if all
+// original pairs do, e.g. the sections of the config.
+func (l *layout) renderEntries(buf *bytes.Buffer, node *yaml.Node, indent int) error {
+	entries := l.entries[node]
+	index := make(map[*yaml.Node]int, len(entries))
+	for i, e := range entries {
+		index[e.node] = i
+	}
+	column := entries[0].node.Column - 1
+	if node.Kind == yaml.SequenceNode {
+		column = indentation(l.lines[entries[0].line])
+	}
+
+	step := 1
+	if node.Kind == yaml.MappingNode {
+		step = 2
+	}
+	previous := -1
+	pending := ""
+	for i := 0; i < len(node.Content); i += step {
+		key, value := node.Content[i], (*yaml.Node)(nil)
+		if step == 2 {
+			value = node.Content[i+1]
+		}
+
+		j, ok := index[key]
+		if !ok {
+			if previous == len(entries)-1 && node.Kind == yaml.MappingNode && l.separated(entries) {
+				buf.WriteString(l.newline)
+			}
+			buf.WriteString(pending)
+			pending = ""
+			err := l.renderFresh(buf, key, value, column, indent)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for k := previous + 1; k < j && pending == ""; k++ {
+			pending = l.join(entries[k].start, entries[k].line)
+		}
+		previous = j
+		e := entries[j]
+		if e.start == e.line {
+			buf.WriteString(pending)
+		}
+		pending = ""
+		l.write(buf, e.start, e.line)
+
+		_, nested := l.entries[value]
+		switch {
+		case l.unchanged(key) && (value == nil || value == e.value && l.unchanged(value)):
+			l.write(buf, e.line, e.end)
+		case nested && value == e.value && len(value.Content) > 0 && l.unchanged(key):
+			l.write(buf, e.line, l.entries[value][0].start)
+			err := l.renderEntries(buf, value, indent)
+			if err != nil {
+				return err
+			}
+		default:
+			err := l.renderFresh(buf, key, value, column, indent)
+			if err != nil {
+				return err
+			}
+		}
+		l.write(buf, e.end, e.next)
+	}
+
+	return nil
+}
+
+// renderFresh writes a new or changed mapping pair or sequence item at the given column.
+// The comments above and below are left out as they are kept in the original lines.
+func (l *layout) renderFresh(buf *bytes.Buffer, key, value *yaml.Node, column, indent int) error {
+	if value == nil {
+		item := *key
+		item.HeadComment, item.FootComment = "", ""
+		return l.encode(buf, &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{&item}}, column, indent)
+	}
+
+	k, v := *key, *value
+	k.HeadComment, k.FootComment = "", ""
+	v.HeadComment, v.FootComment = "", ""
+	if v.Style&yaml.FlowStyle != 0 || len(v.Content) == 0 || (v.Kind != yaml.MappingNode && v.Kind != yaml.SequenceNode) {
+		return l.encode(buf, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&k, &v}}, column, indent)
+	}
+
+	// a block mapping or sequence is written by its entries to indent sequences like the original
+	name, err := yaml.Marshal(&yaml.Node{Kind: k.Kind, Tag: k.Tag, Value: k.Value, Style: k.Style})
+	if err != nil {
+		return err
+	}
+	buf.WriteString(strings.Repeat(" ", column) + strings.TrimSuffix(string(name), "\n") + ":")
+	if comment := k.LineComment + v.LineComment; comment != "" {
+		buf.WriteString(" " + comment)
+	}
+	buf.WriteString(l.newline)
+
+	if v.Kind == yaml.SequenceNode {
+		for _, item := range v.Content {
+			err = l.renderFresh(buf, item, nil, column+l.seqIndent, indent)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i+1 < len(v.Content); i += 2 {
+		err = l.renderFresh(buf, v.Content[i], v.Content[i+1], column+indent, indent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encode writes the YAML encoding of the node with every line indented by the given column
+func (l *layout) encode(buf *bytes.Buffer, node *yaml.Node, column, indent int) error {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(indent)
+	err := enc.Encode(node)
+	if err != nil {
+		return err
+	}
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.Repeat(" ", column)
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		buf.WriteString(prefix + strings.TrimSuffix(line, "\n") + l.newline)
+	}
+
+	return nil
+}
+
+// separated returns true if the original entries are separated by blank lines
+func (l *layout) separated(entries []entry) bool {
+	if len(entries) < 2 {
+		return false
+	}
+
+	for _, e := range entries[:len(entries)-1] {
+		blank := false
+		for i := e.end; i < e.next; i++ {
+			blank = blank || isBlank(l.lines[i])
+		}
+		if !blank {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (l *layout) write(buf *bytes.Buffer, from, to int) {
+	buf.WriteString(l.join(from, to))
+}
+
+func (l *layout) join(from, to int) string {
+	if from >= to {
+		return ""
+	}
+
+	return strings.Join(l.lines[from:to], "")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func isComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+// indentation returns the number of leading spaces of the line
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
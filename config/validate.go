@@ -0,0 +1,280 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/unly/wow-addon-updater/game"
+)
+
+// Problem is an invalid value or key of a config file at its position in the file
+type Problem struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", p.Line, p.Column, p.Message)
+}
+
+// ValidationError contains the problems of a config file
+type ValidationError struct {
+	Path     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = problem.String()
+	}
+
+	return fmt.Sprintf("invalid config file %s:\n  %s", e.Path, strings.Join(lines, "\n  "))
+}
+
+// Checks are the checks of the config values depending on the system and the sources
+type Checks struct {
+	// AddOn returns an error if the addon is not supported, e.g. by any source
+	AddOn func(addon AddOn) error
+	// Path returns an error if the path of an installation of the given flavor is invalid
+	Path func(path string, flavor game.Flavor) error
+}
+
+var (
+	flavors         = []string{string(game.Retail), string(game.Classic), string(game.ClassicTBC)}
+	compatibilities = []string{string(Warn), string(Refuse), string(Ignore)}
+	flavorType      = reflect.TypeOf(game.Retail)
+	compatType      = reflect.TypeOf(Warn)
+)
+
+// Validate reads in the config file of the given path and checks its keys and values including
+// the ones of the given checks. Returns the problems with their position in the file.
+func Validate(path string, checks Checks) ([]Problem, error) {
+	root, err := readNode(path)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := checkNode(root, reflect.TypeOf(Config{}), "config")
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	var c Config
+	if root.Kind != 0 {
+		if err = root.Decode(&c); err != nil {
+			return nil, err
+		}
+	}
+
+	sections := []struct {
+		name   string
+		conf   WowConfig
+		flavor game.Flavor
+	}{
+		{"retail", c.Retail, game.Retail},
+		{"classic", c.Classic, game.Classic},
+	}
+	for _, section := range sections {
+		node := mappingValue(documentContent(root), section.name)
+		if node == nil {
+			continue
+		}
+
+		flavor := section.conf.Flavor
+		if flavor == "" {
+			flavor = section.flavor
+		}
+		problems = append(problems, checkSection(node, section.name, section.conf, flavor, checks)...)
+	}
+
+	if deps := mappingValue(documentContent(root), "dependencies"); deps != nil && checks.AddOn != nil {
+		for i := 0; i+1 < len(deps.Content); i += 2 {
+			key, value := deps.Content[i], deps.Content[i+1]
+			if err := checks.AddOn(AddOn{URL: value.Value}); err != nil {
+				problems = append(problems, problemAt(value, "dependency %s: %v", key.Value, err))
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+
+	return problems, nil
+}
+
+// checkSection returns the problems of the path and the addons of an installation
+func checkSection(node *yaml.Node, name string, conf WowConfig, flavor game.Flavor, checks Checks) []Problem {
+	problems := make([]Problem, 0)
+
+	pathNode := mappingValue(node, "path")
+	if pathNode == nil || conf.Path == "" {
+		if len(conf.AddOns) > 0 {
+			problems = append(problems, problemAt(node, "the %s section has addons but no path", name))
+		}
+	} else if checks.Path != nil {
+		if err := checks.Path(conf.Path, flavor); err != nil {
+			problems = append(problems, problemAt(pathNode, "%v", err))
+		}
+	}
+
+	addons := mappingValue(node, "addons")
+	if addons == nil || checks.AddOn == nil {
+		return problems
+	}
+	for i, addonNode := range addons.Content {
+		if i >= len(conf.AddOns) {
+			break
+		}
+		if err := checks.AddOn(conf.AddOns[i]); err != nil {
+			problems = append(problems, problemAt(addonNode, "addon %s: %v", conf.AddOns[i].URL, err))
+		}
+	}
+
+	return problems
+}
+
+// readNode returns the YAML document of the given file
+func readNode(path string) (*yaml.Node, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	err = yaml.Unmarshal(in, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &root, nil
+}
+
+// checkNode returns the problems of unknown keys and invalid values of the node
+// for the given type. Nodes not matching the kind of the type are left to the decoder.
+func checkNode(node *yaml.Node, t reflect.Type, name string) []Problem {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	problems := make([]Problem, 0)
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, content := range node.Content {
+			problems = append(problems, checkNode(content, t, name)...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			switch t.Kind() {
+			case reflect.Struct:
+				fields := yamlFields(t)
+				field, ok := fields[key.Value]
+				if !ok {
+					problems = append(problems, problemAt(key, "unknown key %s in %s, expected one of %s",
+						key.Value, name, strings.Join(fieldNames(fields), ", ")))
+					continue
+				}
+				problems = append(problems, checkNode(value, field, key.Value)...)
+			case reflect.Map:
+				problems = append(problems, checkNode(value, t.Elem(), name)...)
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice {
+			for _, item := range node.Content {
+				problems = append(problems, checkNode(item, t.Elem(), name)...)
+			}
+		}
+	case yaml.ScalarNode:
+		switch t {
+		case flavorType:
+			problems = append(problems, checkEnum(node, name, flavors)...)
+		case compatType:
+			problems = append(problems, checkEnum(node, name, compatibilities)...)
+		}
+	}
+
+	return problems
+}
+
+func checkEnum(node *yaml.Node, name string, values []string) []Problem {
+	for _, value := range values {
+		if node.Value == value {
+			return nil
+		}
+	}
+
+	return []Problem{problemAt(node, "invalid %s %s, expected one of %s", name, node.Value, strings.Join(values, ", "))}
+}
+
+// yamlFields returns the types of the fields of a struct by their YAML key
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		fields[key] = field.Type
+	}
+
+	return fields
+}
+
+func fieldNames(fields map[string]reflect.Type) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// documentContent returns the root node of the document
+func documentContent(root *yaml.Node) *yaml.Node {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		return root.Content[0]
+	}
+
+	return root
+}
+
+// mappingValue returns the value of the key of a mapping node or nil if there is none
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func problemAt(node *yaml.Node, format string, args ...interface{}) Problem {
+	return Problem{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func TestValidate(t *testing.T) {
+	checks := Checks{
+		AddOn: func(addon AddOn) error {
+			if addon.URL == "unsupported" {
+				return errors.New("not supported")
+			}
+			return nil
+		},
+		Path: func(path string, flavor game.Flavor) error {
+			if path == "missing" {
+				return errors.New("does not exist")
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Problem
+	}{
+		{
+			name: "valid config",
+			content: `
+retail:
+  path: path/to/retail
+  addons:
+    - addon1
+    - url: addon2
+      source: github
+dependencies:
+  LibStub: addon3`,
+			want: []Problem{},
+		},
+		{
+			name:    "empty file",
+			content: "",
+			want:    []Problem{},
+		},
+		{
+			name: "unknown key",
+			content: `
+retail:
+  path: path/to/retail
+sources:
+  order: [github]
+  disable: [direct]`,
+			want: []Problem{
+				{Line: 6, Column: 3, Message: "unknown key disable in sources, expected one of disabled, gitea, gitlab, order, plugins, scrapers"},
+			},
+		},
+		{
+			name: "invalid compatibility",
+			content: `
+retail:
+  path: path/to/retail
+  compatibility: strict`,
+			want: []Problem{
+				{Line: 4, Column: 18, Message: "invalid compatibility strict, expected one of warn, refuse, ignore"},
+			},
+		},
+		{
+			name: "failed checks",
+			content: `
+retail:
+  path: missing
+  addons:
+    - addon1
+    - unsupported
+classic:
+  addons:
+    - addon2
+dependencies:
+  LibStub: unsupported`,
+			want: []Problem{
+				{Line: 3, Column: 9, Message: "does not exist"},
+				{Line: 6, Column: 7, Message: "addon unsupported: not supported"},
+				{Line: 8, Column: 3, Message: "the classic section has addons but no path"},
+				{Line: 11, Column: 12, Message: "dependency LibStub: not supported"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := helpers.TempFile(t, "", []byte(tt.content))
+			defer helpers.DeleteFile(t, file)
+
+			got, err := Validate(file, checks)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Validate("missing.yaml", checks)
+
+		assert.Error(t, err)
+	})
+}
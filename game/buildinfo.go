@@ -0,0 +1,161 @@
+// Package game reads the information of WoW installations.
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/util"
+)
+
+// BuildInfoFile is the name of the file in the root directory of an installation
+// listing the installed products and their versions
+const BuildInfoFile = ".build.info"
+
+// Build is an installed product of the .build.info file
+type Build struct {
+	Branch string
+	Active bool
+	// version of the build, e.g. 9.1.0.39804
+	Version string
+	// product code, e.g. wow or wow_classic
+	Product string
+}
+
+// product codes by the folder of the product in the root directory
+var productFolders = map[string]string{
+	"_retail_":          "wow",
+	"_ptr_":             "wowt",
+	"_beta_":            "wow_beta",
+	"_classic_":         "wow_classic",
+	"_classic_ptr_":     "wow_classic_ptr",
+	"_classic_era_":     "wow_classic_era",
+	"_classic_era_ptr_": "wow_classic_era_ptr",
+}
+
+// product codes by flavor for installations without product folders
+var flavorProducts = map[Flavor]string{
+	Retail:     "wow",
+	Classic:    "wow_classic_era",
+	ClassicTBC: "wow_classic",
+}
+
+// ReadBuildInfo returns the builds of the .build.info file of the given path.
+// The file is a table of | separated columns with a header row of the column names and types.
+func ReadBuildInfo(path string) ([]Build, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("the build info file %s is empty", path)
+	}
+
+	columns := make(map[string]int)
+	for i, header := range strings.Split(scanner.Text(), "|") {
+		name := strings.SplitN(header, "!", 2)[0]
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["version"]; !ok {
+		return nil, fmt.Errorf("the build info file %s has no version column", path)
+	}
+
+	builds := make([]Build, 0)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		values := strings.Split(line, "|")
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(values) {
+				return ""
+			}
+			return strings.TrimSpace(values[i])
+		}
+		builds = append(builds, Build{
+			Branch:  value("branch"),
+			Active:  value("active") == "1",
+			Version: value("version"),
+			Product: value("product"),
+		})
+	}
+
+	return builds, scanner.Err()
+}
+
+// InterfaceVersion returns the interface version of the game for a build version,
+// e.g. 90100 for 9.1.0.39804
+func InterfaceVersion(version string) (int, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 3 {
+		return 0, fmt.Errorf("invalid build version %s", version)
+	}
+
+	numbers := make([]int, 3)
+	for i := range numbers {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 || (i > 0 && n > 99) {
+			return 0, fmt.Errorf("invalid build version %s", version)
+		}
+		numbers[i] = n
+	}
+
+	return numbers[0]*10000 + numbers[1]*100 + numbers[2], nil
+}
+
+// DetectInterface returns the interface version of the installation containing the given
+// directory, e.g. its Interface/AddOns directory. The product is identified by the product
+// folder of the path, e.g. _retail_, or by the given flavor.
+func DetectInterface(dir string, flavor Flavor) (int, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	product := flavorProducts[flavor]
+	for start := dir; ; {
+		buildInfo := filepath.Join(dir, BuildInfoFile)
+		if util.FileExists(buildInfo) {
+			return interfaceOf(buildInfo, product)
+		}
+		if p, ok := productFolders[strings.ToLower(filepath.Base(dir))]; ok {
+			product = p
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return 0, fmt.Errorf("no %s file found for %s", BuildInfoFile, start)
+		}
+		dir = parent
+	}
+}
+
+// interfaceOf returns the interface version of the product in the build info file.
+// Files of older installations without product column list a single build.
+func interfaceOf(buildInfo, product string) (int, error) {
+	builds, err := ReadBuildInfo(buildInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, build := range builds {
+		if build.Product == product || (build.Product == "" && len(builds) == 1) {
+			return InterfaceVersion(build.Version)
+		}
+	}
+
+	return 0, fmt.Errorf("the product %s is not listed in %s", product, buildInfo)
+}
@@ -0,0 +1,138 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+const buildInfo = `Branch!STRING:0|Active!DEC:1|Build Key!HEX:16|CDN Key!HEX:16|Install Key!HEX:16|IM Size!DEC:4|CDN Path!STRING:0|CDN Hosts!STRING:0|CDN Servers!STRING:0|Tags!STRING:0|Armadillo!STRING:0|Last Activated!STRING:0|Version!STRING:0|Product!STRING:0
+eu|1|abc|def|123||tpr/wow|eu.cdn.blizzard.com|http://eu.cdn.blizzard.com/?maxhosts=4|Windows x86_64 EU? enUS speech?:Windows x86_64 EU? enUS text?||2021-09-01T10:00:00Z|9.1.0.39804|wow
+eu|1|abc|def|123||tpr/wow|eu.cdn.blizzard.com|http://eu.cdn.blizzard.com/?maxhosts=4|Windows x86_64 EU? enUS speech?:Windows x86_64 EU? enUS text?||2021-09-01T10:00:00Z|2.5.2.39926|wow_classic
+eu|0|abc|def|123||tpr/wow|eu.cdn.blizzard.com|http://eu.cdn.blizzard.com/?maxhosts=4|Windows x86_64 EU? enUS speech?:Windows x86_64 EU? enUS text?||2021-09-01T10:00:00Z|1.14.0.39802|wow_classic_era
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	assert.NoError(t, os.WriteFile(path, []byte(content), os.FileMode(0666)))
+}
+
+func TestReadBuildInfo(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+
+	t.Run("builds", func(t *testing.T) {
+		path := filepath.Join(dir, "builds", BuildInfoFile)
+		writeFile(t, path, buildInfo)
+
+		builds, err := ReadBuildInfo(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Build{
+			{Branch: "eu", Active: true, Version: "9.1.0.39804", Product: "wow"},
+			{Branch: "eu", Active: true, Version: "2.5.2.39926", Product: "wow_classic"},
+			{Branch: "eu", Active: false, Version: "1.14.0.39802", Product: "wow_classic_era"},
+		}, builds)
+	})
+	t.Run("empty file", func(t *testing.T) {
+		path := filepath.Join(dir, "empty", BuildInfoFile)
+		writeFile(t, path, "")
+
+		_, err := ReadBuildInfo(path)
+
+		assert.Error(t, err)
+	})
+	t.Run("no version column", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid", BuildInfoFile)
+		writeFile(t, path, "Branch!STRING:0|Active!DEC:1\neu|1\n")
+
+		_, err := ReadBuildInfo(path)
+
+		assert.Error(t, err)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ReadBuildInfo(filepath.Join(dir, "missing", BuildInfoFile))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestInterfaceVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    int
+		wantErr bool
+	}{
+		{version: "9.1.0.39804", want: 90100},
+		{version: "9.1.5.40871", want: 90105},
+		{version: "1.14.0.39802", want: 11400},
+		{version: "2.5.2", want: 20502},
+		{version: "9.1", wantErr: true},
+		{version: "9.a.0.1", wantErr: true},
+		{version: "9.100.0.1", wantErr: true},
+		{version: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := InterfaceVersion(tt.version)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetectInterface(t *testing.T) {
+	root := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, root)()
+	writeFile(t, filepath.Join(root, "wow", BuildInfoFile), buildInfo)
+	writeFile(t, filepath.Join(root, "old", BuildInfoFile), "Branch!STRING:0|Active!DEC:1|Version!STRING:0\neu|1|1.13.7.38704\n")
+	for _, dir := range []string{
+		"wow/_retail_/Interface/AddOns",
+		"wow/_classic_/Interface/AddOns",
+		"wow/_classic_era_/Interface/AddOns",
+		"wow/_ptr_/Interface/AddOns",
+		"wow/Interface/AddOns",
+		"old/Interface/AddOns",
+		"none/Interface/AddOns",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), os.ModePerm))
+	}
+
+	tests := []struct {
+		dir     string
+		flavor  Flavor
+		want    int
+		wantErr bool
+	}{
+		{dir: "wow/_retail_/Interface/AddOns", flavor: Retail, want: 90100},
+		{dir: "wow/_classic_/Interface/AddOns", flavor: Classic, want: 20502},
+		{dir: "wow/_classic_era_/Interface/AddOns", flavor: Retail, want: 11400},
+		{dir: "wow/Interface/AddOns", flavor: ClassicTBC, want: 20502},
+		{dir: "wow/Interface/AddOns", flavor: Classic, want: 11400},
+		{dir: "old/Interface/AddOns", flavor: Classic, want: 11307},
+		{dir: "wow/_ptr_/Interface/AddOns", flavor: Retail, wantErr: true},
+		{dir: "none/Interface/AddOns", flavor: Retail, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got, err := DetectInterface(filepath.Join(root, filepath.FromSlash(tt.dir)), tt.flavor)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/unly/wow-addon-updater/util"
+)
+
+// Installation is a product of the game installed in a root directory
+type Installation struct {
+	// product code, e.g. wow or wow_classic
+	Product string
+	// version of the build, e.g. 9.1.0.39804. Empty if not listed in the .build.info file
+	Version string
+	Flavor  Flavor
+	// directory of the installed addons, e.g. World of Warcraft/_retail_/Interface/AddOns
+	AddOnsPath string
+}
+
+// flavors of the products
+var productFlavors = map[string]Flavor{
+	"wow":                 Retail,
+	"wowt":                Retail,
+	"wow_beta":            Retail,
+	"wow_classic":         ClassicTBC,
+	"wow_classic_ptr":     ClassicTBC,
+	"wow_classic_era":     Classic,
+	"wow_classic_era_ptr": Classic,
+}
+
+// root directories of the game relative to a Wine or Proton prefix
+var prefixRoots = []string{
+	"drive_c/Program Files (x86)/World of Warcraft",
+	"drive_c/Program Files/World of Warcraft",
+	"pfx/drive_c/Program Files (x86)/World of Warcraft",
+	"pfx/drive_c/Program Files/World of Warcraft",
+}
+
+// FindRoot returns the root directory of the game containing the .build.info file
+// for the given directory or a Wine or Proton prefix containing the game.
+func FindRoot(dir string) (string, error) {
+	candidates := []string{dir}
+	for _, root := range prefixRoots {
+		candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(root)))
+	}
+
+	for _, candidate := range candidates {
+		if util.FileExists(filepath.Join(candidate, BuildInfoFile)) {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no %s file found in %s", BuildInfoFile, dir)
+}
+
+// Detect returns the installations of the game in the given root directory or Wine or Proton prefix.
+// The installations are the product folders, e.g. _retail_, with their builds of the .build.info file.
+func Detect(dir string) ([]Installation, error) {
+	root, err := FindRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	builds, err := ReadBuildInfo(filepath.Join(root, BuildInfoFile))
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string, len(builds))
+	for _, build := range builds {
+		versions[build.Product] = build.Version
+	}
+
+	installations := make([]Installation, 0)
+	for folder, product := range productFolders {
+		info, err := os.Stat(filepath.Join(root, folder))
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		installations = append(installations, Installation{
+			Product:    product,
+			Version:    versions[product],
+			Flavor:     productFlavors[product],
+			AddOnsPath: filepath.Join(root, folder, "Interface", "AddOns"),
+		})
+	}
+	sort.Slice(installations, func(i, j int) bool {
+		return installations[i].Product < installations[j].Product
+	})
+
+	return installations, nil
+}
+
+// DetectAll returns the installations of all the given root directories or prefixes
+// which contain the game. Directories without the game are skipped.
+func DetectAll(dirs []string) []Installation {
+	installations := make([]Installation, 0)
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		detected, err := Detect(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, installation := range detected {
+			if !seen[installation.AddOnsPath] {
+				seen[installation.AddOnsPath] = true
+				installations = append(installations, installation)
+			}
+		}
+	}
+
+	return installations
+}
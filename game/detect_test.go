@@ -0,0 +1,86 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func writeInstallation(t *testing.T, root string, folders ...string) {
+	t.Helper()
+	writeFile(t, filepath.Join(root, BuildInfoFile), buildInfo)
+	for _, folder := range folders {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, folder, "Interface", "AddOns"), os.ModePerm))
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	root := filepath.Join(dir, "World of Warcraft")
+	writeInstallation(t, root, "_retail_", "_classic_", "_ptr_")
+	wine := filepath.Join(dir, "wine")
+	wineRoot := filepath.Join(wine, "drive_c", "Program Files (x86)", "World of Warcraft")
+	writeInstallation(t, wineRoot, "_classic_era_")
+	proton := filepath.Join(dir, "compatdata", "123")
+	protonRoot := filepath.Join(proton, "pfx", "drive_c", "Program Files", "World of Warcraft")
+	writeInstallation(t, protonRoot, "_retail_")
+
+	t.Run("root directory", func(t *testing.T) {
+		installations, err := Detect(root)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Installation{
+			{
+				Product:    "wow",
+				Version:    "9.1.0.39804",
+				Flavor:     Retail,
+				AddOnsPath: filepath.Join(root, "_retail_", "Interface", "AddOns"),
+			},
+			{
+				Product:    "wow_classic",
+				Version:    "2.5.2.39926",
+				Flavor:     ClassicTBC,
+				AddOnsPath: filepath.Join(root, "_classic_", "Interface", "AddOns"),
+			},
+			{
+				Product:    "wowt",
+				Flavor:     Retail,
+				AddOnsPath: filepath.Join(root, "_ptr_", "Interface", "AddOns"),
+			},
+		}, installations)
+	})
+	t.Run("wine prefix", func(t *testing.T) {
+		installations, err := Detect(wine)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Installation{
+			{
+				Product:    "wow_classic_era",
+				Version:    "1.14.0.39802",
+				Flavor:     Classic,
+				AddOnsPath: filepath.Join(wineRoot, "_classic_era_", "Interface", "AddOns"),
+			},
+		}, installations)
+	})
+	t.Run("proton prefix", func(t *testing.T) {
+		root, err := FindRoot(proton)
+
+		assert.NoError(t, err)
+		assert.Equal(t, protonRoot, root)
+	})
+	t.Run("no game", func(t *testing.T) {
+		_, err := Detect(filepath.Join(dir, "compatdata"))
+
+		assert.Error(t, err)
+	})
+	t.Run("all directories", func(t *testing.T) {
+		installations := DetectAll([]string{root, filepath.Join(dir, "missing"), wine, root})
+
+		assert.Len(t, installations, 4)
+	})
+}
@@ -0,0 +1,13 @@
+package game
+
+// Flavor is the game flavor of an installation.
+type Flavor string
+
+const (
+	// Retail is the current expansion of the game
+	Retail Flavor = "retail"
+	// Classic is the classic game
+	Classic Flavor = "classic"
+	// ClassicTBC is the classic game of The Burning Crusade expansion
+	ClassicTBC Flavor = "classic-tbc"
+)
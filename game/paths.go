@@ -0,0 +1,127 @@
+package game
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveAddOnsPath returns the AddOns directory of the installation for the given path.
+// The path is either the AddOns directory, the Interface directory, the product folder,
+// e.g. _retail_, or the root directory of the game. For the root directory the product
+// folder is picked by the flavor. A missing AddOns directory is created. Returns an error
+// if the path does not exist, is none of these directories or the AddOns directory is not writable.
+func ResolveAddOnsPath(path string, flavor Flavor) (string, error) {
+	dir, err := CheckAddOnsPath(path, flavor)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the AddOns directory %s: %v", dir, err)
+	}
+
+	err = checkWritable(dir)
+	if err != nil {
+		return "", fmt.Errorf("the AddOns directory %s is not writable: %v", dir, err)
+	}
+
+	return dir, nil
+}
+
+// CheckAddOnsPath returns the AddOns directory of the installation for the given path like
+// ResolveAddOnsPath without changing the file system. A missing AddOns directory is valid as
+// long as the directory it is created in is not read only.
+func CheckAddOnsPath(path string, flavor Flavor) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("the path %s does not exist", path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("the path %s is not a directory", path)
+	}
+
+	dir, err := addOnsDir(filepath.Clean(path), flavor)
+	if err != nil {
+		return "", err
+	}
+
+	// the AddOns directory or the closest parent it is created in
+	existing := dir
+	info, err = os.Stat(existing)
+	for os.IsNotExist(err) && filepath.Dir(existing) != existing {
+		existing = filepath.Dir(existing)
+		info, err = os.Stat(existing)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", existing)
+	}
+	if info.Mode().Perm()&0222 == 0 {
+		return "", fmt.Errorf("the AddOns directory %s is not writable: %s is read only", dir, existing)
+	}
+
+	return dir, nil
+}
+
+// addOnsDir returns the AddOns directory for the kind of the given directory
+func addOnsDir(dir string, flavor Flavor) (string, error) {
+	name := strings.ToLower(filepath.Base(dir))
+	switch {
+	case name == "addons":
+		return dir, nil
+	case name == "interface":
+		return filepath.Join(dir, "AddOns"), nil
+	case isDir(filepath.Join(dir, "Interface")):
+		return filepath.Join(dir, "Interface", "AddOns"), nil
+	}
+
+	if _, ok := productFolders[name]; ok {
+		return filepath.Join(dir, "Interface", "AddOns"), nil
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, BuildInfoFile)); err == nil {
+		product := flavorProducts[flavor]
+		for folder, p := range productFolders {
+			if p == product && isDir(filepath.Join(dir, folder)) {
+				return filepath.Join(dir, folder, "Interface", "AddOns"), nil
+			}
+		}
+		return "", fmt.Errorf("the game directory %s has no %s installation", dir, flavor)
+	}
+
+	if hasAddonFolders(dir) {
+		return dir, nil
+	}
+
+	return "", fmt.Errorf("the path %s is neither the game directory nor its Interface or AddOns directory", dir)
+}
+
+// hasAddonFolders returns true if a sub directory of the given directory contains a TOC file
+func hasAddonFolders(dir string) bool {
+	tocs, _ := filepath.Glob(filepath.Join(dir, "*", "*.toc"))
+	return len(tocs) > 0
+}
+
+// checkWritable creates and removes a temporary file in the given directory
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".wow-updater-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	return os.Remove(f.Name())
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,107 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func TestResolveAddOnsPath(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	root := filepath.Join(dir, "World of Warcraft")
+	writeInstallation(t, root, "_retail_", "_classic_era_")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "_classic_"), os.ModePerm))
+	writeFile(t, filepath.Join(dir, "old", "Interface", "AddOns", "MyAddon", "MyAddon.toc"), "")
+	writeFile(t, filepath.Join(dir, "custom", "MyAddon", "MyAddon.toc"), "")
+	writeFile(t, filepath.Join(dir, "file"), "")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), os.ModePerm))
+	retail := filepath.Join(root, "_retail_", "Interface", "AddOns")
+
+	tests := []struct {
+		name    string
+		path    string
+		flavor  Flavor
+		want    string
+		wantErr bool
+	}{
+		{name: "addons directory", path: retail, flavor: Retail, want: retail},
+		{name: "interface directory", path: filepath.Join(root, "_retail_", "Interface"), flavor: Retail, want: retail},
+		{name: "product folder", path: filepath.Join(root, "_retail_"), flavor: Retail, want: retail},
+		{name: "retail root", path: root, flavor: Retail, want: retail},
+		{
+			name:   "classic root",
+			path:   root,
+			flavor: Classic,
+			want:   filepath.Join(root, "_classic_era_", "Interface", "AddOns"),
+		},
+		{name: "missing product", path: root, flavor: ClassicTBC, wantErr: true},
+		{
+			name:   "product folder without interface",
+			path:   filepath.Join(dir, "_classic_"),
+			flavor: ClassicTBC,
+			want:   filepath.Join(dir, "_classic_", "Interface", "AddOns"),
+		},
+		{
+			name:   "root without product folders",
+			path:   filepath.Join(dir, "old"),
+			flavor: Classic,
+			want:   filepath.Join(dir, "old", "Interface", "AddOns"),
+		},
+		{name: "directory with addons", path: filepath.Join(dir, "custom"), flavor: Retail, want: filepath.Join(dir, "custom")},
+		{name: "unknown directory", path: filepath.Join(dir, "empty"), flavor: Retail, wantErr: true},
+		{name: "file", path: filepath.Join(dir, "file"), flavor: Retail, wantErr: true},
+		{name: "missing", path: filepath.Join(dir, "missing"), flavor: Retail, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveAddOnsPath(tt.path, tt.flavor)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.DirExists(t, got)
+		})
+	}
+}
+
+func TestCheckAddOnsPath(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "_classic_"), os.ModePerm))
+	writeFile(t, filepath.Join(dir, "_retail_", "Interface", "AddOns"), "")
+	readOnly := filepath.Join(dir, "_classic_era_")
+	assert.NoError(t, os.MkdirAll(readOnly, os.FileMode(0555)))
+	assert.NoError(t, os.Chmod(readOnly, os.FileMode(0555)))
+	defer os.Chmod(readOnly, os.ModePerm)
+
+	t.Run("missing addons directory", func(t *testing.T) {
+		got, err := CheckAddOnsPath(filepath.Join(dir, "_classic_"), ClassicTBC)
+
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "_classic_", "Interface", "AddOns"), got)
+		assert.NoDirExists(t, filepath.Join(dir, "_classic_", "Interface"))
+	})
+	t.Run("addons file", func(t *testing.T) {
+		_, err := CheckAddOnsPath(filepath.Join(dir, "_retail_"), Retail)
+
+		assert.Error(t, err)
+	})
+	t.Run("read only directory", func(t *testing.T) {
+		_, err := CheckAddOnsPath(readOnly, Classic)
+
+		assert.Error(t, err)
+	})
+	t.Run("missing", func(t *testing.T) {
+		_, err := CheckAddOnsPath(filepath.Join(dir, "missing"), Retail)
+
+		assert.Error(t, err)
+	})
+}
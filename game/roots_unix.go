@@ -0,0 +1,31 @@
+//go:build !windows
+
+package game
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultRoots returns the directories the game is installed to by default. These are the
+// application directory of macOS and the common Wine, Lutris and Proton prefixes on Linux.
+func DefaultRoots() []string {
+	roots := []string{"/Applications/World of Warcraft"}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return roots
+	}
+
+	roots = append(roots,
+		filepath.Join(home, ".wine"),
+		filepath.Join(home, "Games", "world-of-warcraft"),
+		filepath.Join(home, "Games", "battlenet"),
+	)
+	for _, steam := range []string{".steam/steam", ".local/share/Steam"} {
+		prefixes, _ := filepath.Glob(filepath.Join(home, filepath.FromSlash(steam), "steamapps", "compatdata", "*"))
+		roots = append(roots, prefixes...)
+	}
+
+	return roots
+}
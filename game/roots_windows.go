@@ -0,0 +1,20 @@
+//go:build windows
+
+package game
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultRoots returns the directories the game is installed to by default
+func DefaultRoots() []string {
+	roots := make([]string, 0)
+	for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles"} {
+		if dir := os.Getenv(env); dir != "" {
+			roots = append(roots, filepath.Join(dir, "World of Warcraft"))
+		}
+	}
+
+	return roots
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
 	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/updater/sources/direct"
+	"github.com/unly/wow-addon-updater/updater/sources/git"
+	"github.com/unly/wow-addon-updater/updater/sources/gitea"
 	"github.com/unly/wow-addon-updater/updater/sources/github"
+	"github.com/unly/wow-addon-updater/updater/sources/gitlab"
+	"github.com/unly/wow-addon-updater/updater/sources/local"
+	"github.com/unly/wow-addon-updater/updater/sources/plugin"
+	"github.com/unly/wow-addon-updater/updater/sources/scraper"
 	"github.com/unly/wow-addon-updater/updater/sources/tukui"
 	"github.com/unly/wow-addon-updater/updater/sources/wowinterface"
 	"github.com/unly/wow-addon-updater/util"
@@ -20,8 +30,9 @@ const (
 )
 
 var (
-	addonSources = getSources()
+	newSources   = getSources
 	versionsPath = ".versions"
+	confirm      = askUser
 )
 
 func main() {
@@ -49,15 +60,27 @@ func runAndRecover() (err error) {
 }
 
 func run() error {
-	defer closeSources(addonSources)
-
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.PanicOnError)
+	flag.CommandLine.Usage = usage
 	path := flag.String("c", configPath, "path to the config file")
 	flag.Parse()
 	if path == nil {
 		return fmt.Errorf("no configuration file to read in")
 	}
 
+	command := flag.Arg(0)
+	switch command {
+	case "detect":
+		return detectInstallations(*path, flag.Arg(1))
+	case "validate":
+		return validateConfig(*path)
+	case "add", "remove", "pin", "unpin":
+		return editConfig(*path, command, flag.Args()[1:])
+	case "", "update", "scan", "import":
+	default:
+		return fmt.Errorf("unknown command %s", command)
+	}
+
 	log.Println("starting the wow addon manager")
 
 	if !util.FileExists(*path) {
@@ -69,11 +92,29 @@ func run() error {
 		return fmt.Errorf("failed to read in the config file: %v", err)
 	}
 
+	err = resolvePaths(&conf)
+	if err != nil {
+		return err
+	}
+
+	addonSources, err := newSources(conf.Sources)
+	if err != nil {
+		return fmt.Errorf("failed to initialize the addon sources: %v", err)
+	}
+	defer addonSources.Close()
+
 	updater, err := updater.NewUpdater(conf, addonSources, versionsPath)
 	if err != nil {
 		return fmt.Errorf("failed to initialize the updater: %v", err)
 	}
 
+	switch command {
+	case "scan":
+		return scanAddons(updater)
+	case "import":
+		return importAddons(updater, *path)
+	}
+
 	err = updater.UpdateAddons()
 	if err != nil {
 		return fmt.Errorf("failed to update addon versions: %v", err)
@@ -84,6 +125,349 @@ func run() error {
 	return nil
 }
 
+// resolvePaths replaces the paths of the config sections with the AddOns directories of the installations
+func resolvePaths(conf *config.Config) error {
+	sections := []struct {
+		name   string
+		conf   *config.WowConfig
+		flavor game.Flavor
+	}{
+		{"retail", &conf.Retail, game.Retail},
+		{"classic", &conf.Classic, game.Classic},
+	}
+
+	for _, section := range sections {
+		if section.conf.Path == "" {
+			if len(section.conf.AddOns) > 0 {
+				return fmt.Errorf("the %s section has addons but no path", section.name)
+			}
+			continue
+		}
+
+		flavor := section.conf.Flavor
+		if flavor == "" {
+			flavor = section.flavor
+		}
+		path, err := game.ResolveAddOnsPath(section.conf.Path, flavor)
+		if err != nil {
+			return fmt.Errorf("invalid path of the %s section: %v", section.name, err)
+		}
+
+		if path != section.conf.Path {
+			log.Printf("using the AddOns directory %s for the %s section\n", path, section.name)
+			section.conf.Path = path
+		}
+	}
+
+	return nil
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: %s [-c config] [command]\n\n", os.Args[0])
+	fmt.Fprintln(out, "commands:")
+	fmt.Fprintln(out, "  update    update the addons of the config (default)")
+	fmt.Fprintln(out, "  scan      list the installed addons missing in the config")
+	fmt.Fprintln(out, "  import    add the installed addons missing in the config to it")
+	fmt.Fprintln(out, "  detect    find the game installations in the default or given directory")
+	fmt.Fprintln(out, "  validate  check the config file without updating")
+	fmt.Fprintln(out, "  add       add the given addon URL to the retail or the given section, e.g. add gh:owner/repo classic")
+	fmt.Fprintln(out, "  remove    remove the given addon URL from the config")
+	fmt.Fprintln(out, "  pin       keep the installed version of the given addon URL")
+	fmt.Fprintln(out, "  unpin     update the given addon URL again")
+	fmt.Fprintln(out, "\nflags:")
+	flag.PrintDefaults()
+}
+
+// validateConfig checks the keys and values of the config file, the paths of the installations
+// and whether the addons are supported by the sources. Logs each problem with its position.
+func validateConfig(path string) error {
+	conf, err := config.ReadConfig(path)
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		return logProblems(path, validationErr.Problems)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read in the config file: %v", err)
+	}
+
+	addonSources, err := newSources(conf.Sources)
+	if err != nil {
+		return fmt.Errorf("failed to initialize the addon sources: %v", err)
+	}
+	defer addonSources.Close()
+
+	problems, err := config.Validate(path, config.Checks{
+		AddOn: func(addon config.AddOn) error {
+			return updater.CheckAddOn(addonSources, addon)
+		},
+		Path: func(path string, flavor game.Flavor) error {
+			_, err := game.CheckAddOnsPath(path, flavor)
+			return err
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to validate the config file: %v", err)
+	}
+	if len(problems) > 0 {
+		return logProblems(path, problems)
+	}
+
+	log.Printf("the config file %s is valid\n", path)
+
+	return nil
+}
+
+func logProblems(path string, problems []config.Problem) error {
+	for _, problem := range problems {
+		log.Printf("%s: %s\n", path, problem)
+	}
+
+	return fmt.Errorf("the config file %s has %d problem(s)", path, len(problems))
+}
+
+var editedActions = map[string]string{
+	"add":    "added",
+	"remove": "removed",
+	"pin":    "pinned",
+	"unpin":  "unpinned",
+}
+
+// editConfig adds, removes, pins or unpins the addon of the given arguments in the config file.
+// The file is edited in place keeping its comments and formatting.
+func editConfig(path, command string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("the %s command needs an addon URL", command)
+	}
+	url := args[0]
+
+	editor, err := config.ReadEditor(path)
+	if err != nil {
+		return fmt.Errorf("failed to read in the config file: %v", err)
+	}
+
+	switch command {
+	case "add":
+		section := "retail"
+		if len(args) > 1 {
+			section = args[1]
+		}
+		added, err := addAddon(path, editor, section, url)
+		if err != nil {
+			return err
+		}
+		if !added {
+			log.Printf("the %s section already contains %s\n", section, url)
+			return nil
+		}
+	case "remove":
+		if editor.RemoveAddOn(url) == 0 {
+			return fmt.Errorf("the addon %s is not in the config", url)
+		}
+	default:
+		if editor.PinAddOn(url, command == "pin") == 0 {
+			log.Printf("no addon %s to %s in the config\n", url, command)
+			return nil
+		}
+	}
+
+	err = editor.Write(path)
+	if err != nil {
+		return fmt.Errorf("failed to write the config file: %v", err)
+	}
+	log.Printf("%s %s in %s\n", editedActions[command], url, path)
+
+	return nil
+}
+
+// addAddon adds the addon to the section after checking that a source supports it
+func addAddon(path string, editor *config.Editor, section, url string) (bool, error) {
+	var conf config.Config
+	if util.FileExists(path) {
+		var err error
+		conf, err = config.ReadConfig(path)
+		if err != nil {
+			return false, fmt.Errorf("failed to read in the config file: %v", err)
+		}
+	}
+
+	addonSources, err := newSources(conf.Sources)
+	if err != nil {
+		return false, fmt.Errorf("failed to initialize the addon sources: %v", err)
+	}
+	defer addonSources.Close()
+
+	addon := config.AddOn{URL: url}
+	err = updater.CheckAddOn(addonSources, addon)
+	if err != nil {
+		return false, err
+	}
+
+	return editor.AddAddOn(section, addon)
+}
+
+func scanAddons(u *updater.Updater) error {
+	retail, classic, err := u.ScanAddons()
+	if err != nil {
+		return fmt.Errorf("failed to scan the addon directories: %v", err)
+	}
+
+	log.Printf("found %d retail and %d classic addon(s) missing in the config\n", len(retail), len(classic))
+
+	return nil
+}
+
+func importAddons(u *updater.Updater, path string) error {
+	retail, classic, err := u.ImportAddons()
+	if err != nil {
+		return fmt.Errorf("failed to import the installed addons: %v", err)
+	}
+	if len(retail) == 0 && len(classic) == 0 {
+		log.Println("no addons to import")
+		return nil
+	}
+
+	editor, err := config.ReadEditor(path)
+	if err != nil {
+		return fmt.Errorf("failed to read in the config file: %v", err)
+	}
+	sections := []struct {
+		name   string
+		addons []config.AddOn
+	}{
+		{"retail", retail},
+		{"classic", classic},
+	}
+	for _, section := range sections {
+		for _, addon := range section.addons {
+			if _, err = editor.AddAddOn(section.name, addon); err != nil {
+				return err
+			}
+		}
+	}
+	err = editor.Write(path)
+	if err != nil {
+		return fmt.Errorf("failed to write the config file: %v", err)
+	}
+
+	log.Printf("imported %d retail and %d classic addon(s) to %s\n", len(retail), len(classic), path)
+
+	return nil
+}
+
+func detectInstallations(path, dir string) error {
+	var installations []game.Installation
+	if dir == "" {
+		installations = game.DetectAll(game.DefaultRoots())
+	} else {
+		var err error
+		installations, err = game.Detect(dir)
+		if err != nil {
+			return fmt.Errorf("failed to detect the installations: %v", err)
+		}
+	}
+
+	if len(installations) == 0 {
+		log.Println("no installations found. run detect with the directory of the game, e.g. detect \"path/to/World of Warcraft\"")
+		return nil
+	}
+	for _, installation := range installations {
+		log.Printf("found %s %s (%s) in %s\n", installation.Product, installation.Version, installation.Flavor, installation.AddOnsPath)
+	}
+
+	var conf config.Config
+	if util.FileExists(path) {
+		var err error
+		conf, err = config.ReadConfig(path)
+		if err != nil {
+			return fmt.Errorf("failed to read in the config file: %v", err)
+		}
+	}
+
+	if !applyInstallations(&conf, installations) {
+		log.Println("the config contains paths for all installations")
+		return nil
+	}
+	if !confirm(fmt.Sprintf("write the installations to %s?", path)) {
+		return nil
+	}
+
+	editor, err := config.ReadEditor(path)
+	if err != nil {
+		return fmt.Errorf("failed to read in the config file: %v", err)
+	}
+	sections := []struct {
+		name string
+		conf config.WowConfig
+	}{
+		{"retail", conf.Retail},
+		{"classic", conf.Classic},
+	}
+	for _, section := range sections {
+		if section.conf.Path == "" {
+			continue
+		}
+		if err = editor.SetInstallation(section.name, section.conf.Path, section.conf.Flavor); err != nil {
+			return err
+		}
+	}
+	err = editor.Write(path)
+	if err != nil {
+		return fmt.Errorf("failed to write the config file: %v", err)
+	}
+	log.Printf("wrote the installations to %s\n", path)
+
+	return nil
+}
+
+// applyInstallations sets the paths of the config sections without a path to the detected installations.
+// The classic section gets the latest classic expansion. Returns false if no section is changed.
+func applyInstallations(conf *config.Config, installations []game.Installation) bool {
+	changed := false
+	if conf.Retail.Path == "" {
+		if installation, ok := findInstallation(installations, "wow"); ok {
+			conf.Retail.Path = installation.AddOnsPath
+			changed = true
+		}
+	}
+
+	if conf.Classic.Path == "" {
+		for _, product := range []string{"wow_classic", "wow_classic_era"} {
+			installation, ok := findInstallation(installations, product)
+			if !ok {
+				continue
+			}
+
+			conf.Classic.Path = installation.AddOnsPath
+			if installation.Flavor != game.Classic {
+				conf.Classic.Flavor = installation.Flavor
+			}
+			changed = true
+			break
+		}
+	}
+
+	return changed
+}
+
+func findInstallation(installations []game.Installation, product string) (game.Installation, bool) {
+	for _, installation := range installations {
+		if installation.Product == product {
+			return installation, true
+		}
+	}
+
+	return game.Installation{}, false
+}
+
+func askUser(question string) bool {
+	log.Printf("%s [y/N]\n", question)
+	var answer string
+	_, _ = fmt.Scanln(&answer)
+
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
+
 func generateDefaultConfig(path string) error {
 	err := config.CreateDefaultConfig(path)
 	if err != nil {
@@ -96,28 +480,53 @@ func generateDefaultConfig(path string) error {
 	return nil
 }
 
-func getSources() []updater.UpdateSource {
-	sources := make([]updater.UpdateSource, 0)
-	tukuiSource, err := tukui.New(new(http.Client))
-	if err != nil {
-		panic(err)
+func getSources(conf config.SourcesConfig) (*updater.Registry, error) {
+	client := new(http.Client)
+	type factory struct {
+		name string
+		new  func() (updater.UpdateSource, error)
 	}
-	sources = append(sources, tukuiSource)
-	wowinterfaceSource, err := wowinterface.New(new(http.Client))
-	if err != nil {
-		panic(err)
+	factories := []factory{
+		{"tukui", func() (updater.UpdateSource, error) { return tukui.New(client) }},
+		{"wowinterface", func() (updater.UpdateSource, error) { return wowinterface.New(client) }},
+		{"github", func() (updater.UpdateSource, error) { return github.New(client) }},
+		{"gitlab", func() (updater.UpdateSource, error) { return gitlab.New(client, conf.GitLab) }},
+		{"gitea", func() (updater.UpdateSource, error) { return gitea.New(client, conf.Gitea) }},
+		{"git", func() (updater.UpdateSource, error) { return git.New() }},
+		{"local", func() (updater.UpdateSource, error) { return local.New() }},
 	}
-	sources = append(sources, wowinterfaceSource)
-	githubSource, err := github.New(new(http.Client))
-	if err != nil {
-		panic(err)
+	for _, scraperConf := range conf.Scrapers {
+		scraperConf := scraperConf
+		factories = append(factories, factory{scraperConf.Name, func() (updater.UpdateSource, error) { return scraper.New(client, scraperConf) }})
 	}
-	sources = append(sources, githubSource)
-	return sources
-}
+	for _, pluginConf := range conf.Plugins {
+		pluginConf := pluginConf
+		factories = append(factories, factory{pluginConf.Name, func() (updater.UpdateSource, error) { return plugin.New(client, pluginConf) }})
+	}
+	// matches any link to a zip archive, hence the last source to look up
+	factories = append(factories, factory{"direct", func() (updater.UpdateSource, error) { return direct.New(client) }})
 
-func closeSources(sources []updater.UpdateSource) {
-	for _, s := range sources {
-		s.Close()
+	registry := updater.NewRegistry()
+	for _, f := range factories {
+		source, err := f.new()
+		if err != nil {
+			registry.Close()
+			return nil, err
+		}
+
+		err = registry.Register(f.name, source)
+		if err != nil {
+			source.Close()
+			registry.Close()
+			return nil, err
+		}
 	}
+
+	err := registry.Configure(conf.Order, conf.Disabled)
+	if err != nil {
+		registry.Close()
+		return nil, err
+	}
+
+	return registry, nil
 }
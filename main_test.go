@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -9,6 +10,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
 	"github.com/unly/wow-addon-updater/updater"
 	"github.com/unly/wow-addon-updater/updater/mocks"
 	"github.com/unly/wow-addon-updater/util"
@@ -64,22 +67,103 @@ func Test_generateDefaultConfig(t *testing.T) {
 	}
 }
 
-func Test_closeSources(t *testing.T) {
-	m1 := new(mocks.MockUpdateSource)
-	m1.On("Close").Return(nil)
-	m2 := new(mocks.MockUpdateSource)
-	m2.On("Close").Return(nil)
-	addonSources = []updater.UpdateSource{
-		m1,
-		m2,
+func mockSources(sources ...updater.UpdateSource) func(config.SourcesConfig) (*updater.Registry, error) {
+	return func(config.SourcesConfig) (*updater.Registry, error) {
+		registry := updater.NewRegistry()
+		for i, source := range sources {
+			if err := registry.Register(fmt.Sprintf("source%d", i), source); err != nil {
+				return nil, err
+			}
+		}
+
+		return registry, nil
 	}
+}
+
+func Test_getSources(t *testing.T) {
+	t.Run("default sources", func(t *testing.T) {
+		sources, err := getSources(config.SourcesConfig{})
+		defer sources.Close()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"tukui", "wowinterface", "github", "gitlab", "gitea", "git", "local", "direct"}, sources.Names())
+	})
+	t.Run("invalid gitlab instance", func(t *testing.T) {
+		_, err := getSources(config.SourcesConfig{
+			GitLab: []config.HostConfig{{URL: "invalid"}},
+		})
+
+		assert.Error(t, err)
+	})
+	t.Run("scraper sources", func(t *testing.T) {
+		sources, err := getSources(config.SourcesConfig{
+			Scrapers: []config.ScraperConfig{
+				{Name: "a", URL: `^https://a\.example\.com/.+$`, Version: "#version", Download: "a"},
+				{Name: "b", URL: `^https://b\.example\.com/.+$`, Version: "#version", Download: "a"},
+			},
+		})
+		defer sources.Close()
 
-	closeSources(addonSources)
+		assert.NoError(t, err)
+		assert.Len(t, sources.Names(), 10)
+		assert.Equal(t, []string{"b", "direct"}, sources.Matching("https://b.example.com/addon.zip"))
+	})
+	t.Run("invalid scraper source", func(t *testing.T) {
+		_, err := getSources(config.SourcesConfig{
+			Scrapers: []config.ScraperConfig{{Name: "a"}},
+		})
 
-	assert.Equal(t, 1, len(m1.Calls))
-	assert.Equal(t, "Close", m1.Calls[0].Method)
-	assert.Equal(t, 1, len(m2.Calls))
-	assert.Equal(t, "Close", m2.Calls[0].Method)
+		assert.Error(t, err)
+	})
+	t.Run("invalid plugin source", func(t *testing.T) {
+		_, err := getSources(config.SourcesConfig{
+			Plugins: []config.PluginConfig{{Name: "a", Command: "not-existing-plugin"}},
+		})
+
+		assert.Error(t, err)
+	})
+	t.Run("ordered and disabled sources", func(t *testing.T) {
+		sources, err := getSources(config.SourcesConfig{
+			Order:    []string{"direct", "github"},
+			Disabled: []string{"tukui", "wowinterface", "gitlab", "gitea", "git"},
+		})
+		defer sources.Close()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"direct", "github", "local"}, sources.Names())
+	})
+	t.Run("duplicate disabled source", func(t *testing.T) {
+		sources, err := getSources(config.SourcesConfig{
+			Disabled: []string{"github", "github"},
+		})
+		defer sources.Close()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"tukui", "wowinterface", "gitlab", "gitea", "git", "local", "direct"}, sources.Names())
+	})
+	t.Run("unknown source in order", func(t *testing.T) {
+		_, err := getSources(config.SourcesConfig{
+			Order: []string{"unknown"},
+		})
+
+		assert.Error(t, err)
+	})
+	t.Run("duplicate source name", func(t *testing.T) {
+		_, err := getSources(config.SourcesConfig{
+			Scrapers: []config.ScraperConfig{
+				{Name: "github", URL: `^https://a\.example\.com/.+$`, Version: "#version", Download: "a"},
+			},
+		})
+
+		assert.Error(t, err)
+	})
+	t.Run("invalid gitea instance", func(t *testing.T) {
+		_, err := getSources(config.SourcesConfig{
+			Gitea: []config.HostConfig{{URL: "invalid"}},
+		})
+
+		assert.Error(t, err)
+	})
 }
 
 func Test_runAndRecover(t *testing.T) {
@@ -89,13 +173,13 @@ func Test_runAndRecover(t *testing.T) {
 		checks        func()
 		teardown      helpers.TearDown
 	}
-	oldSources := addonSources
+	oldSources := newSources
 	oldArgs := os.Args
 	defer func() {
-		addonSources = oldSources
+		newSources = oldSources
 		os.Args = oldArgs
 	}()
-	addonSources = []updater.UpdateSource{}
+	newSources = mockSources()
 
 	tests := []func() *mainTest{
 		func() *mainTest {
@@ -132,20 +216,21 @@ func Test_runAndRecover(t *testing.T) {
 			}
 		},
 		func() *mainTest {
+			dir := helpers.TempDir(t)
 			content := []byte(`
 classic:
-  path: path/to/classic
+  path: ` + filepath.ToSlash(dir) + `/Interface
   addons:
     - addon1
     - addon2`)
-			file := helpers.TempFile(t, "", content)
-			err := os.WriteFile(file, content, os.FileMode(0666))
+			err := os.MkdirAll(filepath.Join(dir, "Interface"), os.ModePerm)
 			assert.NoError(t, err)
+			file := helpers.TempFile(t, dir, content)
 
 			return &mainTest{
 				args:          []string{"-c", file},
 				errorExpected: true,
-				teardown:      helpers.DeleteFile(t, file),
+				teardown:      helpers.DeleteDir(t, dir),
 			}
 		},
 		func() *mainTest {
@@ -154,19 +239,20 @@ classic:
 			m.On("GetLatestVersion", mock.Anything).Return("1.2.3", nil)
 			m.On("DownloadAddon", mock.Anything, mock.Anything).Return(nil)
 			m.On("Close").Return(nil)
-			addonSources = []updater.UpdateSource{
-				m,
-			}
+			newSources = mockSources(m)
 
+			dir := helpers.TempDir(t)
+			addonDir := filepath.Join(dir, "Interface", "AddOns")
+			err := os.MkdirAll(addonDir, os.ModePerm)
+			assert.NoError(t, err)
 			content := []byte(`
 classic:
-  path: path/to/classic
+  path: ` + filepath.ToSlash(addonDir) + `
   addons:
     - addon1
     - addon2`)
-			dir := helpers.TempDir(t)
 			file := helpers.TempFile(t, dir, content)
-			err := os.WriteFile(file, content, os.FileMode(0666))
+			err = os.WriteFile(file, content, os.FileMode(0666))
 			assert.NoError(t, err)
 			oldVersionsPath := versionsPath
 			versionsPath = filepath.Join(dir, ".versions")
@@ -175,11 +261,46 @@ classic:
 				args:          []string{"-c", file},
 				errorExpected: false,
 				checks: func() {
-					assert.Equal(t, 7, len(m.Calls))
+					assert.Equal(t, 9, len(m.Calls))
 				},
 				teardown: func() {
 					helpers.DeleteDir(t, dir)
-					addonSources = []updater.UpdateSource{}
+					newSources = mockSources()
+					versionsPath = oldVersionsPath
+				},
+			}
+		},
+		func() *mainTest {
+			file := helpers.TempFile(t, "", []byte("retail:\n  path: path/to/retail\n"))
+
+			return &mainTest{
+				args:          []string{"-c", file, "unknown"},
+				errorExpected: true,
+				teardown:      helpers.DeleteFile(t, file),
+			}
+		},
+		func() *mainTest {
+			dir := helpers.TempDir(t)
+			addonDir := filepath.Join(dir, "AddOns")
+			err := os.MkdirAll(filepath.Join(addonDir, "Addon"), os.ModePerm)
+			assert.NoError(t, err)
+			err = os.WriteFile(filepath.Join(addonDir, "Addon", "Addon.toc"), []byte("## Title: Addon\n"), os.FileMode(0666))
+			assert.NoError(t, err)
+			content := []byte("retail:\n  path: " + filepath.ToSlash(addonDir) + "\n  compatibility: ignore\n")
+			file := helpers.TempFile(t, dir, content)
+			oldVersionsPath := versionsPath
+			versionsPath = filepath.Join(dir, ".versions")
+
+			return &mainTest{
+				args:          []string{"-c", file, "import"},
+				errorExpected: false,
+				checks: func() {
+					actual, err := os.ReadFile(file)
+					assert.NoError(t, err)
+					assert.Equal(t, content, actual)
+				},
+				teardown: func() {
+					helpers.DeleteDir(t, dir)()
 					versionsPath = oldVersionsPath
 				},
 			}
@@ -208,3 +329,269 @@ classic:
 		tt.teardown()
 	}
 }
+
+func Test_applyInstallations(t *testing.T) {
+	retail := game.Installation{Product: "wow", Flavor: game.Retail, AddOnsPath: "retail"}
+	tbc := game.Installation{Product: "wow_classic", Flavor: game.ClassicTBC, AddOnsPath: "tbc"}
+	era := game.Installation{Product: "wow_classic_era", Flavor: game.Classic, AddOnsPath: "era"}
+	ptr := game.Installation{Product: "wowt", Flavor: game.Retail, AddOnsPath: "ptr"}
+
+	tests := []struct {
+		name          string
+		conf          config.Config
+		installations []game.Installation
+		want          config.Config
+		wantChanged   bool
+	}{
+		{
+			name:          "all installations",
+			installations: []game.Installation{ptr, era, tbc, retail},
+			want: config.Config{
+				Retail:  config.WowConfig{Path: "retail"},
+				Classic: config.WowConfig{Path: "tbc", Flavor: game.ClassicTBC},
+			},
+			wantChanged: true,
+		},
+		{
+			name:          "classic era",
+			installations: []game.Installation{era},
+			want: config.Config{
+				Classic: config.WowConfig{Path: "era"},
+			},
+			wantChanged: true,
+		},
+		{
+			name:          "existing paths",
+			conf:          config.Config{Retail: config.WowConfig{Path: "path/to/retail"}},
+			installations: []game.Installation{retail, ptr},
+			want:          config.Config{Retail: config.WowConfig{Path: "path/to/retail"}},
+			wantChanged:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := applyInstallations(&tt.conf, tt.installations)
+
+			assert.Equal(t, tt.wantChanged, changed)
+			assert.Equal(t, tt.want, tt.conf)
+		})
+	}
+}
+
+func Test_detectInstallations(t *testing.T) {
+	oldConfirm := confirm
+	defer func() {
+		confirm = oldConfirm
+	}()
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	root := filepath.Join(dir, "World of Warcraft")
+	err := os.MkdirAll(filepath.Join(root, "_retail_"), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(root, game.BuildInfoFile), []byte("Version!STRING:0|Product!STRING:0\n9.1.0.39804|wow\n"), os.FileMode(0666))
+	assert.NoError(t, err)
+	file := filepath.Join(dir, "config.yaml")
+
+	t.Run("declined", func(t *testing.T) {
+		confirm = func(string) bool { return false }
+
+		err := detectInstallations(file, root)
+
+		assert.NoError(t, err)
+		assert.NoFileExists(t, file)
+	})
+	t.Run("confirmed", func(t *testing.T) {
+		confirm = func(string) bool { return true }
+
+		err := detectInstallations(file, root)
+
+		assert.NoError(t, err)
+		conf, err := config.ReadConfig(file)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, "_retail_", "Interface", "AddOns"), conf.Retail.Path)
+	})
+	t.Run("keeps comments", func(t *testing.T) {
+		confirm = func(string) bool { return true }
+		err := os.WriteFile(file, []byte("retail:\n  # my addons\n  addons:\n    - addon1\n"), os.FileMode(0666))
+		assert.NoError(t, err)
+
+		err = detectInstallations(file, root)
+
+		assert.NoError(t, err)
+		actual, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		want := "retail:\n  path: " + filepath.Join(root, "_retail_", "Interface", "AddOns") + "\n  # my addons\n  addons:\n    - addon1\n"
+		assert.Equal(t, want, string(actual))
+	})
+	t.Run("no game", func(t *testing.T) {
+		err := detectInstallations(file, filepath.Join(dir, "missing"))
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_resolvePaths(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	err := os.MkdirAll(filepath.Join(dir, "Interface"), os.ModePerm)
+	assert.NoError(t, err)
+
+	t.Run("interface directory", func(t *testing.T) {
+		conf := config.Config{Retail: config.WowConfig{Path: filepath.Join(dir, "Interface")}}
+
+		err := resolvePaths(&conf)
+
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "Interface", "AddOns"), conf.Retail.Path)
+		assert.Equal(t, "", conf.Classic.Path)
+	})
+	t.Run("missing path", func(t *testing.T) {
+		conf := config.Config{Classic: config.WowConfig{Path: filepath.Join(dir, "missing")}}
+
+		err := resolvePaths(&conf)
+
+		assert.Error(t, err)
+	})
+	t.Run("addons without path", func(t *testing.T) {
+		conf := config.Config{Retail: config.WowConfig{AddOns: []config.AddOn{{URL: "addon"}}}}
+
+		err := resolvePaths(&conf)
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_validateConfig(t *testing.T) {
+	oldSources := newSources
+	defer func() {
+		newSources = oldSources
+	}()
+	m := new(mocks.MockUpdateSource)
+	m.On("GetURLRegex").Return(regexp.MustCompile(`^addon.+`))
+	m.On("Close").Return(nil)
+	newSources = mockSources(m)
+
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	addonDir := filepath.Join(dir, "AddOns")
+	assert.NoError(t, os.MkdirAll(addonDir, os.ModePerm))
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid config",
+			content: "retail:\n  path: " + filepath.ToSlash(addonDir) + "\n  addons:\n    - addon1\n",
+		},
+		{
+			name:    "unknown key",
+			content: "retail:\n  path: " + filepath.ToSlash(addonDir) + "\n  addon:\n    - addon1\n",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported addon",
+			content: "retail:\n  path: " + filepath.ToSlash(addonDir) + "\n  addons:\n    - other\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing path",
+			content: "retail:\n  path: " + filepath.ToSlash(filepath.Join(dir, "missing")) + "\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := helpers.TempFile(t, dir, []byte(tt.content))
+
+			err := validateConfig(file)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	t.Run("missing file", func(t *testing.T) {
+		err := validateConfig(filepath.Join(dir, "missing.yaml"))
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_editConfig(t *testing.T) {
+	oldSources := newSources
+	defer func() {
+		newSources = oldSources
+	}()
+	m := new(mocks.MockUpdateSource)
+	m.On("GetURLRegex").Return(regexp.MustCompile(`^addon.+`))
+	m.On("Close").Return(nil)
+	newSources = mockSources(m)
+
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	file := filepath.Join(dir, "config.yaml")
+	content := "retail:\n  # my addons\n  addons:\n    - addon1\n"
+	assert.NoError(t, os.WriteFile(file, []byte(content), os.FileMode(0666)))
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "no url", command: "add", wantErr: true, want: content},
+		{name: "unsupported addon", command: "add", args: []string{"other"}, wantErr: true, want: content},
+		{name: "unknown section", command: "add", args: []string{"addon2", "tbc"}, wantErr: true, want: content},
+		{name: "existing addon", command: "add", args: []string{"addon1"}, want: content},
+		{
+			name:    "add to classic",
+			command: "add",
+			args:    []string{"addon2", "classic"},
+			want:    "retail:\n  # my addons\n  addons:\n    - addon1\nclassic:\n  addons:\n    - addon2\n",
+		},
+		{
+			name:    "pin",
+			command: "pin",
+			args:    []string{"addon1"},
+			want:    "retail:\n  # my addons\n  addons:\n    - url: addon1\n      pinned: true\nclassic:\n  addons:\n    - addon2\n",
+		},
+		{
+			name:    "unpin",
+			command: "unpin",
+			args:    []string{"addon1"},
+			want:    "retail:\n  # my addons\n  addons:\n    - addon1\nclassic:\n  addons:\n    - addon2\n",
+		},
+		{
+			name:    "remove",
+			command: "remove",
+			args:    []string{"addon2"},
+			want:    "retail:\n  # my addons\n  addons:\n    - addon1\nclassic:\n  addons: []\n",
+		},
+		{
+			name:    "remove missing addon",
+			command: "remove",
+			args:    []string{"addon2"},
+			wantErr: true,
+			want:    "retail:\n  # my addons\n  addons:\n    - addon1\nclassic:\n  addons: []\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := editConfig(file, tt.command, tt.args)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			actual, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(actual))
+		})
+	}
+}
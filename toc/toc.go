@@ -0,0 +1,223 @@
+// Package toc reads the TOC files describing WoW addons.
+package toc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/game"
+)
+
+// File is the content of an addon's TOC file
+type File struct {
+	// name of the addon, i.e. the file name without the flavor suffix and extension
+	Name string
+	// flavor suffix of the file name, e.g. Mainline for MyAddon_Mainline.toc
+	Suffix string
+	// interface versions of the game the addon supports, e.g. 90100
+	Interface []int
+	Title     string
+	Version   string
+	Notes     string
+	Author    string
+	// names of the addons required to load the addon
+	Dependencies []string
+	// names of the addons to load before the addon if installed
+	OptionalDeps               []string
+	SavedVariables             []string
+	SavedVariablesPerCharacter []string
+	LoadOnDemand               bool
+	// all metadata fields by their lower case name, including the X-* fields
+	Fields map[string]string
+	// files of the addon to load in order with slashes as separator
+	Files []string
+}
+
+// suffixes of the TOC files by flavor in the order the game client looks them up
+var suffixes = map[game.Flavor][]string{
+	game.Retail:     {"Mainline"},
+	game.Classic:    {"Vanilla", "Classic"},
+	game.ClassicTBC: {"TBC", "BCC", "Classic"},
+}
+
+// knownSuffixes are all suffixes of flavor-specific TOC files
+var knownSuffixes = []string{"Mainline", "Vanilla", "Classic", "TBC", "BCC", "Wrath", "WOTLKC"}
+
+// Field returns the value of the metadata field of the given name ignoring its case,
+// e.g. X-WoWI-ID, or an empty string if there is none.
+func (f *File) Field(name string) string {
+	return f.Fields[strings.ToLower(name)]
+}
+
+// Parse reads in the TOC file content of the given reader.
+// Invalid interface versions are skipped like the game client does.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{
+		Fields: make(map[string]string),
+		Files:  make([]string, 0),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			f.Files = append(f.Files, strings.ReplaceAll(line, `\`, "/"))
+			continue
+		}
+		if !strings.HasPrefix(line, "##") {
+			continue
+		}
+
+		field := strings.SplitN(strings.TrimPrefix(line, "##"), ":", 2)
+		if len(field) != 2 {
+			continue
+		}
+		f.setField(strings.TrimSpace(field[0]), strings.TrimSpace(field[1]))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *File) setField(name, value string) {
+	key := strings.ToLower(name)
+	f.Fields[key] = value
+
+	switch {
+	case key == "interface":
+		f.Interface = parseInterface(value)
+	case key == "title":
+		f.Title = value
+	case key == "version":
+		f.Version = value
+	case key == "notes":
+		f.Notes = value
+	case key == "author":
+		f.Author = value
+	case key == "optionaldeps":
+		f.OptionalDeps = append(f.OptionalDeps, splitList(value)...)
+	// the game treats any field starting with Dep as required dependencies
+	case key == "requireddeps" || strings.HasPrefix(key, "dep"):
+		f.Dependencies = append(f.Dependencies, splitList(value)...)
+	case key == "savedvariables":
+		f.SavedVariables = splitList(value)
+	case key == "savedvariablespercharacter":
+		f.SavedVariablesPerCharacter = splitList(value)
+	case key == "loadondemand":
+		f.LoadOnDemand = value == "1"
+	}
+}
+
+// parseInterface returns the valid ones of the comma separated interface versions
+func parseInterface(value string) []int {
+	versions := make([]int, 0)
+	for _, v := range splitList(value) {
+		if version, err := strconv.Atoi(v); err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions
+}
+
+// splitList returns the trimmed, non-empty values of a comma separated list
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// ParseFile reads in the TOC file of the given path and sets the addon name
+// and flavor suffix from its file name.
+func ParseFile(path string) (*File, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	f, err := Parse(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	f.Name, f.Suffix = SplitName(filepath.Base(path))
+
+	return f, nil
+}
+
+// SplitName returns the addon name and the flavor suffix of a TOC file name, e.g.
+// MyAddon and Mainline for MyAddon_Mainline.toc or MyAddon-Mainline.toc.
+// The suffix is empty for a TOC file of all flavors.
+func SplitName(fileName string) (string, string) {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	for _, suffix := range knownSuffixes {
+		for _, sep := range []string{"_", "-"} {
+			if len(name) > len(sep+suffix) && strings.EqualFold(name[len(name)-len(sep+suffix):], sep+suffix) {
+				return name[:len(name)-len(sep+suffix)], suffix
+			}
+		}
+	}
+
+	return name, ""
+}
+
+// Find returns the path of the TOC file the game client loads for the addon folder
+// and the given flavor. That is the flavor-specific one or the TOC file named like the folder.
+// Returns false if there is none.
+func Find(dir string, flavor game.Flavor) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+
+	folder := filepath.Base(dir)
+	candidates := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".toc") {
+			continue
+		}
+
+		name, suffix := SplitName(entry.Name())
+		if strings.EqualFold(name, folder) {
+			candidates[strings.ToLower(suffix)] = filepath.Join(dir, entry.Name())
+		}
+	}
+
+	for _, suffix := range append(suffixes[flavor], "") {
+		if path, ok := candidates[strings.ToLower(suffix)]; ok {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// Read returns the TOC file the game client loads for the addon folder and the given flavor
+func Read(dir string, flavor game.Flavor) (*File, error) {
+	path, ok := Find(dir, flavor)
+	if !ok {
+		return nil, fmt.Errorf("no %s toc file found in %s", flavor, dir)
+	}
+
+	return ParseFile(path)
+}
@@ -0,0 +1,209 @@
+package toc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+const sampleTOC = "\ufeff## Interface: 90100, 20502\n" +
+	"## Title: My |cff00ff00Addon|r\n" +
+	"## Notes: Does things\n" +
+	"## Author: Someone\n" +
+	"## version: 1.2.3\n" +
+	"## Dependencies: LibStub, Ace3\n" +
+	"## RequiredDeps: ParentAddon\n" +
+	"## OptionalDeps: Masque , ,LibSharedMedia-3.0\n" +
+	"## SavedVariables: MyAddonDB\n" +
+	"## SavedVariablesPerCharacter: MyAddonCharDB, MyAddonOptions\n" +
+	"## LoadOnDemand: 1\n" +
+	"## X-WoWI-ID: 24608\n" +
+	"## X-Curse-Project-ID: 1234\n" +
+	"# a comment\n" +
+	"## no field\n" +
+	"\n" +
+	"Libs\\LibStub.lua\n" +
+	"  Core.lua  \n" +
+	"Options.xml\n"
+
+func TestParse(t *testing.T) {
+	t.Run("sample toc", func(t *testing.T) {
+		f, err := Parse(strings.NewReader(sampleTOC))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{90100, 20502}, f.Interface)
+		assert.Equal(t, "My |cff00ff00Addon|r", f.Title)
+		assert.Equal(t, "Does things", f.Notes)
+		assert.Equal(t, "Someone", f.Author)
+		assert.Equal(t, "1.2.3", f.Version)
+		assert.Equal(t, []string{"LibStub", "Ace3", "ParentAddon"}, f.Dependencies)
+		assert.Equal(t, []string{"Masque", "LibSharedMedia-3.0"}, f.OptionalDeps)
+		assert.Equal(t, []string{"MyAddonDB"}, f.SavedVariables)
+		assert.Equal(t, []string{"MyAddonCharDB", "MyAddonOptions"}, f.SavedVariablesPerCharacter)
+		assert.True(t, f.LoadOnDemand)
+		assert.Equal(t, "24608", f.Field("X-WoWI-ID"))
+		assert.Equal(t, "1234", f.Field("x-curse-project-id"))
+		assert.Equal(t, "", f.Field("X-Wago-ID"))
+		assert.Equal(t, []string{"Libs/LibStub.lua", "Core.lua", "Options.xml"}, f.Files)
+	})
+	t.Run("empty toc", func(t *testing.T) {
+		f, err := Parse(strings.NewReader(""))
+
+		assert.NoError(t, err)
+		assert.Empty(t, f.Interface)
+		assert.Empty(t, f.Dependencies)
+		assert.Empty(t, f.Files)
+		assert.False(t, f.LoadOnDemand)
+	})
+	t.Run("invalid interface", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("## Title: Addon\n## Interface: 90100, abc\n## Version: 1.0\n"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{90100}, f.Interface)
+		assert.Equal(t, "1.0", f.Version)
+	})
+}
+
+func TestSplitName(t *testing.T) {
+	tests := []struct {
+		fileName   string
+		wantName   string
+		wantSuffix string
+	}{
+		{fileName: "MyAddon.toc", wantName: "MyAddon"},
+		{fileName: "MyAddon_Mainline.toc", wantName: "MyAddon", wantSuffix: "Mainline"},
+		{fileName: "MyAddon-Classic.toc", wantName: "MyAddon", wantSuffix: "Classic"},
+		{fileName: "MyAddon_vanilla.toc", wantName: "MyAddon", wantSuffix: "Vanilla"},
+		{fileName: "MyAddon_TBC.toc", wantName: "MyAddon", wantSuffix: "TBC"},
+		{fileName: "MyAddon-BCC.toc", wantName: "MyAddon", wantSuffix: "BCC"},
+		{fileName: "MyAddon_Wrath.toc", wantName: "MyAddon", wantSuffix: "Wrath"},
+		{fileName: "My_Addon.toc", wantName: "My_Addon"},
+		{fileName: "Classic.toc", wantName: "Classic"},
+		{fileName: "_Classic.toc", wantName: "_Classic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			name, suffix := SplitName(tt.fileName)
+
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantSuffix, suffix)
+		})
+	}
+}
+
+func writeTOCFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		err := os.WriteFile(filepath.Join(dir, file), []byte("## Title: "+file+"\n"), os.FileMode(0666))
+		assert.NoError(t, err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, root)()
+
+	tests := []struct {
+		name   string
+		files  []string
+		flavor game.Flavor
+		want   string
+	}{
+		{
+			name:   "plain toc",
+			files:  []string{"MyAddon.toc"},
+			flavor: game.Retail,
+			want:   "MyAddon.toc",
+		},
+		{
+			name:   "retail toc",
+			files:  []string{"MyAddon.toc", "MyAddon_Mainline.toc", "MyAddon_Vanilla.toc"},
+			flavor: game.Retail,
+			want:   "MyAddon_Mainline.toc",
+		},
+		{
+			name:   "classic toc",
+			files:  []string{"MyAddon.toc", "MyAddon-Classic.toc", "MyAddon_Mainline.toc"},
+			flavor: game.Classic,
+			want:   "MyAddon-Classic.toc",
+		},
+		{
+			name:   "vanilla before classic toc",
+			files:  []string{"MyAddon_Classic.toc", "MyAddon_Vanilla.toc"},
+			flavor: game.Classic,
+			want:   "MyAddon_Vanilla.toc",
+		},
+		{
+			name:   "tbc toc",
+			files:  []string{"MyAddon_Classic.toc", "MyAddon-BCC.toc"},
+			flavor: game.ClassicTBC,
+			want:   "MyAddon-BCC.toc",
+		},
+		{
+			name:   "only other flavor",
+			files:  []string{"MyAddon_Mainline.toc"},
+			flavor: game.Classic,
+		},
+		{
+			name:   "toc of another name",
+			files:  []string{"Other.toc"},
+			flavor: game.Retail,
+		},
+		{
+			name:   "no toc",
+			flavor: game.Retail,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(root, string(rune('a'+i)), "MyAddon")
+			assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+			writeTOCFiles(t, dir, tt.files...)
+
+			path, ok := Find(dir, tt.flavor)
+
+			if tt.want == "" {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, filepath.Join(dir, tt.want), path)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	root := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, root)()
+	dir := filepath.Join(root, "MyAddon")
+	assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	writeTOCFiles(t, dir, "MyAddon.toc", "MyAddon_Mainline.toc")
+
+	t.Run("flavor toc", func(t *testing.T) {
+		f, err := Read(dir, game.Retail)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "MyAddon", f.Name)
+		assert.Equal(t, "Mainline", f.Suffix)
+		assert.Equal(t, "MyAddon_Mainline.toc", f.Title)
+	})
+	t.Run("plain toc", func(t *testing.T) {
+		f, err := Read(dir, game.Classic)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "MyAddon", f.Name)
+		assert.Equal(t, "", f.Suffix)
+		assert.Equal(t, "MyAddon.toc", f.Title)
+	})
+	t.Run("no toc", func(t *testing.T) {
+		_, err := Read(root, game.Retail)
+
+		assert.Error(t, err)
+	})
+}
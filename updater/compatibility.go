@@ -0,0 +1,151 @@
+package updater
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/toc"
+	"github.com/unly/wow-addon-updater/util"
+)
+
+// getInterface returns the configured interface version of the installation or the one
+// detected from its .build.info file if the compatibility check is enabled. Returns 0 if it is unknown.
+func getInterface(conf config.WowConfig, flavor game.Flavor) int {
+	if conf.Interface != 0 || !checksCompatibility(conf.Compatibility) || conf.Path == "" {
+		return conf.Interface
+	}
+
+	version, err := game.DetectInterface(conf.Path, flavor)
+	if err != nil {
+		log.Printf("skipping the compatibility check of the addons in %s: %v\n", conf.Path, err)
+		return 0
+	}
+
+	return version
+}
+
+// checksCompatibility returns true if the installed addons are checked for their compatibility
+// with the interface version of the installation
+func (g *gameUpdater) checksCompatibility() bool {
+	return g.interfaceVersion != 0 && checksCompatibility(g.config.Compatibility)
+}
+
+// checksCompatibility returns true if the check is enabled. It is opt-in as
+// many working addons lag behind the interface version of the game.
+func checksCompatibility(compatibility config.Compatibility) bool {
+	return compatibility == config.Warn || compatibility == config.Refuse
+}
+
+// installChecked downloads the addon to a temporary directory and checks the TOC files of
+// its folders before moving them to the installation. Returns false if the addon is refused.
+func (g *gameUpdater) installChecked(addonURL string, source UpdateSource) (bool, error) {
+	dir, err := os.MkdirTemp("", "wow-updater")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+
+	err = g.downloadAddon(addonURL, dir, source)
+	if err != nil {
+		return false, err
+	}
+
+	problems, err := checkCompatibility(dir, g.flavor, g.interfaceVersion)
+	if err != nil {
+		return false, err
+	}
+	for _, problem := range problems {
+		g.report(addonURL, problem)
+	}
+	if len(problems) > 0 && g.config.Compatibility == config.Refuse {
+		g.report(addonURL, "refused to install the incompatible version")
+		return false, nil
+	}
+
+	return true, moveFolders(dir, g.config.Path)
+}
+
+// report adds a problem of the addon to the summary of the run
+func (g *gameUpdater) report(addonURL, problem string) {
+	log.Printf("%s: %s\n", addonURL, problem)
+	g.summary = append(g.summary, fmt.Sprintf("%s: %s", addonURL, problem))
+}
+
+// checkCompatibility returns the problems of the addon folders in the given directory
+// with the flavor and interface version of the installation. Folders without any
+// TOC file, e.g. libraries, are skipped.
+func checkCompatibility(dir string, flavor game.Flavor, interfaceVersion int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]string, 0)
+	for _, entry := range entries {
+		folder := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() {
+			continue
+		}
+		if tocs, _ := filepath.Glob(filepath.Join(folder, "*.toc")); len(tocs) == 0 {
+			continue
+		}
+
+		path, ok := toc.Find(folder, flavor)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s has no toc file for %s", entry.Name(), flavor))
+			continue
+		}
+
+		f, err := toc.ParseFile(path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if len(f.Interface) == 0 {
+			problems = append(problems, fmt.Sprintf("%s has no interface version", entry.Name()))
+		} else if !supportsInterface(f.Interface, interfaceVersion) {
+			problems = append(problems, fmt.Sprintf("%s is out of date with interface version %v instead of %d",
+				entry.Name(), f.Interface, interfaceVersion))
+		}
+	}
+
+	return problems, nil
+}
+
+// supportsInterface returns true if one of the interface versions of a TOC file
+// is of the major version of the game and not older than its interface version
+func supportsInterface(versions []int, interfaceVersion int) bool {
+	for _, v := range versions {
+		if v/10000 == interfaceVersion/10000 && v >= interfaceVersion {
+			return true
+		}
+	}
+
+	return false
+}
+
+// moveFolders replaces the folders of the installation with the ones in the given directory
+func moveFolders(dir, dest string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		src := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			err = util.CopyDir(src, filepath.Join(dest, entry.Name()))
+		} else {
+			err = util.CopyFile(src, filepath.Join(dest, entry.Name()))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/updater/mocks"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func writeAddonFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NoError(t, os.WriteFile(path, []byte(content), os.FileMode(0666)))
+	}
+}
+
+func Test_checkCompatibility(t *testing.T) {
+	root := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, root)()
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		flavor game.Flavor
+		want   []string
+	}{
+		{
+			name:   "compatible",
+			files:  map[string]string{"MyAddon/MyAddon.toc": "## Interface: 90100\n"},
+			flavor: game.Retail,
+			want:   []string{},
+		},
+		{
+			name:   "newer patch",
+			files:  map[string]string{"MyAddon/MyAddon.toc": "## Interface: 90105\n"},
+			flavor: game.Retail,
+			want:   []string{},
+		},
+		{
+			name: "flavor toc",
+			files: map[string]string{
+				"MyAddon/MyAddon.toc":          "## Interface: 11400\n",
+				"MyAddon/MyAddon_Mainline.toc": "## Interface: 11400, 90100\n",
+			},
+			flavor: game.Retail,
+			want:   []string{},
+		},
+		{
+			name:   "out of date",
+			files:  map[string]string{"MyAddon/MyAddon.toc": "## Interface: 90005\n"},
+			flavor: game.Retail,
+			want:   []string{"MyAddon is out of date with interface version [90005] instead of 90100"},
+		},
+		{
+			name:   "other major version",
+			files:  map[string]string{"MyAddon/MyAddon.toc": "## Interface: 100000\n"},
+			flavor: game.Retail,
+			want:   []string{"MyAddon is out of date with interface version [100000] instead of 90100"},
+		},
+		{
+			name:   "no interface",
+			files:  map[string]string{"MyAddon/MyAddon.toc": "## Title: MyAddon\n"},
+			flavor: game.Retail,
+			want:   []string{"MyAddon has no interface version"},
+		},
+		{
+			name:   "no flavor toc",
+			files:  map[string]string{"MyAddon/MyAddon_Vanilla.toc": "## Interface: 11400\n"},
+			flavor: game.Retail,
+			want:   []string{"MyAddon has no toc file for retail"},
+		},
+		{
+			name: "folders without toc",
+			files: map[string]string{
+				"Libs/LibStub.lua": "",
+				"readme.txt":       "",
+			},
+			flavor: game.Retail,
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(root, tt.name)
+			writeAddonFiles(t, dir, tt.files)
+
+			problems, err := checkCompatibility(dir, tt.flavor, 90100)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, problems)
+		})
+	}
+}
+
+func Test_updateAddon_Compatibility(t *testing.T) {
+	url := "example.com/addon"
+	newSource := func(toc string) *mocks.MockUpdateSource {
+		m := &mocks.MockUpdateSource{}
+		m.On("GetLatestVersion", url).Return("1.2.3", nil)
+		m.On("DownloadAddon", url, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			writeAddonFiles(t, args.String(1), map[string]string{
+				"MyAddon/MyAddon.toc": toc,
+				"MyAddon/Core.lua":    "new",
+			})
+		})
+		return m
+	}
+	newUpdater := func(compatibility config.Compatibility) (*gameUpdater, helpers.TearDown) {
+		dir := helpers.TempDir(t)
+		writeAddonFiles(t, dir, map[string]string{
+			"MyAddon/Core.lua": "old",
+			"MyAddon/Old.lua":  "old",
+		})
+		return &gameUpdater{
+			config: config.WowConfig{
+				Path:          dir,
+				Compatibility: compatibility,
+			},
+			flavor:           game.Retail,
+			interfaceVersion: 90100,
+		}, helpers.DeleteDir(t, dir)
+	}
+
+	t.Run("compatible", func(t *testing.T) {
+		g, tearDown := newUpdater(config.Refuse)
+		defer tearDown()
+
+		err := g.updateAddon(url, url, newSource("## Interface: 90100\n"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1.2.3", g.getCurrentVersion(url))
+		assert.Empty(t, g.summary)
+		assert.FileExists(t, filepath.Join(g.config.Path, "MyAddon", "MyAddon.toc"))
+		assert.NoFileExists(t, filepath.Join(g.config.Path, "MyAddon", "Old.lua"))
+	})
+	t.Run("warn", func(t *testing.T) {
+		g, tearDown := newUpdater(config.Warn)
+		defer tearDown()
+
+		err := g.updateAddon(url, url, newSource("## Interface: 90005\n"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1.2.3", g.getCurrentVersion(url))
+		assert.Equal(t, []string{url + ": MyAddon is out of date with interface version [90005] instead of 90100"}, g.summary)
+		content, err := os.ReadFile(filepath.Join(g.config.Path, "MyAddon", "Core.lua"))
+		assert.NoError(t, err)
+		assert.Equal(t, "new", string(content))
+	})
+	t.Run("refuse", func(t *testing.T) {
+		g, tearDown := newUpdater(config.Refuse)
+		defer tearDown()
+
+		err := g.updateAddon(url, url, newSource("## Title: MyAddon\n"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", g.getCurrentVersion(url))
+		assert.Equal(t, []string{
+			url + ": MyAddon has no interface version",
+			url + ": refused to install the incompatible version",
+		}, g.summary)
+		content, err := os.ReadFile(filepath.Join(g.config.Path, "MyAddon", "Core.lua"))
+		assert.NoError(t, err)
+		assert.Equal(t, "old", string(content))
+	})
+	for name, compatibility := range map[string]config.Compatibility{"ignore": config.Ignore, "not configured": ""} {
+		compatibility := compatibility
+		t.Run(name, func(t *testing.T) {
+			g, tearDown := newUpdater(compatibility)
+			defer tearDown()
+
+			err := g.updateAddon(url, url, newSource("## Title: MyAddon\n"))
+
+			assert.NoError(t, err)
+			assert.Equal(t, "1.2.3", g.getCurrentVersion(url))
+			assert.Empty(t, g.summary)
+			assert.FileExists(t, filepath.Join(g.config.Path, "MyAddon", "Old.lua"))
+		})
+	}
+}
+
+func Test_getInterface(t *testing.T) {
+	root := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, root)()
+	writeAddonFiles(t, root, map[string]string{
+		".build.info":                     "Branch!STRING:0|Active!DEC:1|Version!STRING:0|Product!STRING:0\neu|1|9.1.0.39804|wow\n",
+		"_retail_/Interface/AddOns/a.txt": "",
+	})
+	path := filepath.Join(root, "_retail_", "Interface", "AddOns")
+
+	assert.Equal(t, 90200, getInterface(config.WowConfig{Path: path, Interface: 90200}, game.Retail))
+	assert.Equal(t, 90100, getInterface(config.WowConfig{Path: path, Compatibility: config.Warn}, game.Retail))
+	assert.Equal(t, 0, getInterface(config.WowConfig{Path: path}, game.Retail))
+	assert.Equal(t, 0, getInterface(config.WowConfig{Path: path, Compatibility: config.Ignore}, game.Retail))
+	other := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, other)()
+	assert.Equal(t, 0, getInterface(config.WowConfig{Path: other, Compatibility: config.Warn}, game.Retail))
+}
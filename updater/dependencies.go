@@ -0,0 +1,250 @@
+package updater
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/toc"
+)
+
+// knownDependencies are the URLs of addons other addons commonly depend on
+// by their lower case folder name
+var knownDependencies = map[string]string{
+	"elvui": "https://www.tukui.org/download.php?ui=elvui",
+	"tukui": "https://www.tukui.org/download.php?ui=tukui",
+}
+
+// dependency is a required addon of installed addons which is not installed
+type dependency struct {
+	name string
+	// folder names of the addons requiring the dependency
+	requiredBy []string
+}
+
+// getDependencies returns the URLs of the known and the configured dependencies by their
+// lower case folder name. Short references of the config are expanded.
+func getDependencies(sources *Registry, configured map[string]string) (map[string]string, error) {
+	dependencies := make(map[string]string, len(knownDependencies)+len(configured))
+	for name, url := range knownDependencies {
+		dependencies[name] = url
+	}
+
+	for name, ref := range configured {
+		url, err := sources.Expand(ref)
+		if err != nil {
+			return nil, err
+		}
+		dependencies[strings.ToLower(name)] = url
+	}
+
+	return dependencies, nil
+}
+
+// missingDependencies returns the required dependencies of the addon folders in the given
+// directory which are not installed, sorted by name. The addons of the game itself, e.g.
+// Blizzard_..., are skipped. Returns no dependencies if the directory does not exist.
+func missingDependencies(dir string, flavor game.Flavor) ([]dependency, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	installed, err := installedFolders(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make(map[string]*dependency)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		f, err := toc.Read(filepath.Join(dir, entry.Name()), flavor)
+		if err != nil {
+			continue
+		}
+
+		for _, name := range f.Dependencies {
+			key := strings.ToLower(name)
+			if installed[key] || strings.HasPrefix(key, "blizzard_") {
+				continue
+			}
+
+			if _, ok := missing[key]; !ok {
+				missing[key] = &dependency{name: name}
+			}
+			missing[key].requiredBy = append(missing[key].requiredBy, entry.Name())
+		}
+	}
+
+	dependencies := make([]dependency, 0, len(missing))
+	for _, dep := range missing {
+		dependencies = append(dependencies, *dep)
+	}
+	sort.Slice(dependencies, func(i, j int) bool {
+		return strings.ToLower(dependencies[i].name) < strings.ToLower(dependencies[j].name)
+	})
+
+	return dependencies, nil
+}
+
+// installDependencies installs the missing dependencies of the installed addons with a known URL
+// until all of them are installed. Dependencies are attempted once per run. Failed installations
+// are added to the summary instead of stopping the run.
+func (g *gameUpdater) installDependencies(sources *Registry, attempted map[string]bool) error {
+	for {
+		missing, err := missingDependencies(g.config.Path, g.flavor)
+		if err != nil {
+			return err
+		}
+
+		installed := false
+		for _, dep := range missing {
+			key := strings.ToLower(dep.name)
+			url, ok := g.dependencies[key]
+			if !ok || attempted[key] {
+				continue
+			}
+			attempted[key] = true
+
+			log.Printf("installing dependency %s of %s\n", dep.name, strings.Join(dep.requiredBy, ", "))
+			// forget the version of a dependency whose folders were deleted after its installation
+			g.untrack(url)
+			err = g.updateConfiguredAddon(sources, config.AddOn{URL: url})
+			if err != nil {
+				g.report(url, fmt.Sprintf("failed to install dependency %s: %v", dep.name, err))
+				continue
+			}
+			installed = true
+		}
+
+		if !installed {
+			return nil
+		}
+	}
+}
+
+// updateInstalledDependencies updates the dependencies installed in previous runs which are
+// not part of the config. Dependencies whose folder was deleted are left to installDependencies.
+// Failed updates are added to the summary instead of stopping the run.
+func (g *gameUpdater) updateInstalledDependencies(sources *Registry, attempted map[string]bool) error {
+	names := make([]string, 0, len(g.dependencies))
+	for name := range g.dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	folders, err := installedFolders(g.config.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		url := g.dependencies[name]
+		if g.isConfigured(url) || !g.isTracked(url) || !folders[name] {
+			continue
+		}
+
+		attempted[name] = true
+		err := g.updateConfiguredAddon(sources, config.AddOn{URL: url})
+		if err != nil {
+			g.report(url, fmt.Sprintf("failed to update dependency %s: %v", name, err))
+		}
+	}
+
+	return nil
+}
+
+// installedFolders returns the lower case names of the addon folders in the given directory.
+// Returns no folders if the directory does not exist.
+func installedFolders(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			folders[strings.ToLower(entry.Name())] = true
+		}
+	}
+
+	return folders, nil
+}
+
+// reportMissingDependencies adds the dependencies still missing at the end of the run to the summary
+func (g *gameUpdater) reportMissingDependencies() error {
+	missing, err := missingDependencies(g.config.Path, g.flavor)
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range missing {
+		problem := fmt.Sprintf("missing dependency %s, add its url to the dependencies of the config", dep.name)
+		if _, ok := g.dependencies[strings.ToLower(dep.name)]; ok {
+			problem = fmt.Sprintf("missing dependency %s after installing it", dep.name)
+		}
+		g.report(strings.Join(dep.requiredBy, ", "), problem)
+	}
+
+	return nil
+}
+
+// orderAddons returns the configured addons with the ones other addons may depend on first
+func (g *gameUpdater) orderAddons() []config.AddOn {
+	isDependency := make(map[string]bool, len(g.dependencies))
+	for _, url := range g.dependencies {
+		isDependency[url] = true
+	}
+
+	addons := make([]config.AddOn, len(g.config.AddOns))
+	copy(addons, g.config.AddOns)
+	sort.SliceStable(addons, func(i, j int) bool {
+		return isDependency[addons[i].URL] && !isDependency[addons[j].URL]
+	})
+
+	return addons
+}
+
+func (g *gameUpdater) isConfigured(url string) bool {
+	for _, addon := range g.config.AddOns {
+		if addon.URL == url {
+			return true
+		}
+	}
+
+	return false
+}
+
+// untrack removes the versions of the addon so that it is installed again
+func (g *gameUpdater) untrack(url string) {
+	for key, add := range g.versions {
+		if add.URL == url {
+			delete(g.versions, key)
+		}
+	}
+}
+
+func (g *gameUpdater) isTracked(url string) bool {
+	for _, add := range g.versions {
+		if add.URL == url {
+			return true
+		}
+	}
+
+	return false
+}
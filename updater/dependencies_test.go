@@ -0,0 +1,210 @@
+package updater
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func Test_getDependencies(t *testing.T) {
+	sources := newRegistry(t, expander{mockSource("example.com/.+")})
+
+	t.Run("known and configured", func(t *testing.T) {
+		dependencies, err := getDependencies(sources, map[string]string{
+			"LibStub": "ex:libstub",
+			"ElvUI":   "https://example.com/elvui",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"libstub": "example.com/libstub",
+			"elvui":   "https://example.com/elvui",
+			"tukui":   knownDependencies["tukui"],
+		}, dependencies)
+	})
+	t.Run("invalid reference", func(t *testing.T) {
+		_, err := getDependencies(sources, map[string]string{"LibStub": "ex:"})
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_missingDependencies(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	writeAddonFiles(t, dir, map[string]string{
+		"PluginA/PluginA.toc":          "## Dependencies: ElvUI, Blizzard_Calendar, libstub\n",
+		"PluginB/PluginB.toc":          "## RequiredDeps: ElvUI\n## OptionalDeps: Masque\n",
+		"PluginB/PluginB_Vanilla.toc":  "## RequiredDeps: ClassicOnly\n",
+		"LibStub/LibStub.toc":          "",
+		"NoTOC/Core.lua":               "",
+		"Broken/Broken.toc":            "## Interface: abc\n## Dependencies: Unknown\n",
+		"Classic/Classic_Vanilla.toc":  "## Dependencies: ClassicOnly\n",
+		"Another/Another.toc":          "## Dep1: Details\n",
+		"Another/Another_Mainline.toc": "## Dep1: Details\n",
+	})
+
+	t.Run("retail", func(t *testing.T) {
+		missing, err := missingDependencies(dir, game.Retail)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []dependency{
+			{name: "Details", requiredBy: []string{"Another"}},
+			{name: "ElvUI", requiredBy: []string{"PluginA", "PluginB"}},
+			{name: "Unknown", requiredBy: []string{"Broken"}},
+		}, missing)
+	})
+	t.Run("classic", func(t *testing.T) {
+		missing, err := missingDependencies(dir, game.Classic)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []dependency{
+			{name: "ClassicOnly", requiredBy: []string{"Classic", "PluginB"}},
+			{name: "Details", requiredBy: []string{"Another"}},
+			{name: "ElvUI", requiredBy: []string{"PluginA"}},
+			{name: "Unknown", requiredBy: []string{"Broken"}},
+		}, missing)
+	})
+	t.Run("missing directory", func(t *testing.T) {
+		missing, err := missingDependencies("path/to/missing/dir", game.Retail)
+
+		assert.NoError(t, err)
+		assert.Empty(t, missing)
+	})
+}
+
+func Test_orderAddons(t *testing.T) {
+	g := &gameUpdater{
+		config: config.WowConfig{
+			AddOns: []config.AddOn{
+				{URL: "example.com/plugin"},
+				{URL: "example.com/other"},
+				{URL: "example.com/elvui"},
+				{URL: "example.com/lib"},
+			},
+		},
+		dependencies: map[string]string{
+			"elvui": "example.com/elvui",
+			"lib":   "example.com/lib",
+		},
+	}
+
+	assert.Equal(t, []config.AddOn{
+		{URL: "example.com/elvui"},
+		{URL: "example.com/lib"},
+		{URL: "example.com/plugin"},
+		{URL: "example.com/other"},
+	}, g.orderAddons())
+}
+
+func Test_updateAddons_Dependencies(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	writeAddonFiles(t, dir, map[string]string{
+		"Installed/Installed.toc": "## Dependencies: Lib\n",
+		"Tracked/Tracked.toc":     "",
+	})
+
+	m := mockSource("example.com/.+")
+	install := func(url string, files map[string]string) {
+		m.On("GetLatestVersion", url).Return("1.0", nil)
+		m.On("DownloadAddon", url, dir).Return(nil).Run(func(args mock.Arguments) {
+			writeAddonFiles(t, dir, files)
+		})
+	}
+	install("example.com/lib", map[string]string{"Lib/Lib.toc": ""})
+	install("example.com/plugin", map[string]string{"Plugin/Plugin.toc": "## Dependencies: ElvUI, Unknown\n"})
+	install("example.com/elvui", map[string]string{"ElvUI/ElvUI.toc": "## Dependencies: ElvUI_Libs\n"})
+	install("example.com/elvui-libs", map[string]string{"ElvUI_Libs/ElvUI_Libs.toc": ""})
+	install("example.com/tracked", map[string]string{"Tracked/Tracked.toc": ""})
+
+	g := &gameUpdater{
+		config: config.WowConfig{
+			Path:   dir,
+			AddOns: []config.AddOn{{URL: "example.com/plugin"}},
+		},
+		flavor: game.Retail,
+		versions: map[string]addon{
+			"example.com/tracked": {Name: "example.com/tracked", URL: "example.com/tracked", Version: "0.9"},
+		},
+		dependencies: map[string]string{
+			"lib":        "example.com/lib",
+			"elvui":      "example.com/elvui",
+			"elvui_libs": "example.com/elvui-libs",
+			"tracked":    "example.com/tracked",
+			"other":      "example.com/other",
+		},
+	}
+
+	err := g.updateAddons(newRegistry(t, m))
+
+	assert.NoError(t, err)
+	var downloads []string
+	for _, call := range m.Calls {
+		if call.Method == "DownloadAddon" {
+			downloads = append(downloads, call.Arguments.String(0))
+		}
+	}
+	assert.Equal(t, []string{
+		"example.com/tracked",
+		"example.com/lib",
+		"example.com/plugin",
+		"example.com/elvui",
+		"example.com/elvui-libs",
+	}, downloads)
+	assert.Equal(t, "1.0", g.getCurrentVersion("example.com/elvui-libs"))
+	assert.Equal(t, []string{
+		"Plugin: missing dependency Unknown, add its url to the dependencies of the config",
+	}, g.summary)
+}
+
+func Test_updateAddons_DependencyProblems(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	writeAddonFiles(t, dir, map[string]string{
+		"Installed/Installed.toc": "## Dependencies: Lib, Broken\n",
+	})
+
+	m := mockSource("example.com/.+")
+	m.On("GetLatestVersion", "example.com/lib").Return("1.0", nil)
+	m.On("DownloadAddon", "example.com/lib", dir).Return(nil).Run(func(args mock.Arguments) {
+		writeAddonFiles(t, dir, map[string]string{"Lib/Lib.toc": ""})
+	})
+	m.On("GetLatestVersion", "example.com/broken").Return("", errors.New("not found"))
+	m.On("GetLatestVersion", "example.com/addon").Return("2.0", nil)
+	m.On("DownloadAddon", "example.com/addon", dir).Return(nil)
+
+	g := &gameUpdater{
+		config: config.WowConfig{
+			Path:   dir,
+			AddOns: []config.AddOn{{URL: "example.com/addon"}},
+		},
+		flavor: game.Retail,
+		versions: map[string]addon{
+			// the folder of the tracked dependency was deleted
+			"example.com/lib":    {Name: "example.com/lib", URL: "example.com/lib", Version: "1.0"},
+			"example.com/broken": {Name: "example.com/broken", URL: "example.com/broken", Version: "0.9"},
+		},
+		dependencies: map[string]string{
+			"lib":    "example.com/lib",
+			"broken": "example.com/broken",
+		},
+	}
+
+	err := g.updateAddons(newRegistry(t, m))
+
+	assert.NoError(t, err)
+	assert.DirExists(t, filepath.Join(dir, "Lib"))
+	assert.Equal(t, "2.0", g.getCurrentVersion("example.com/addon"))
+	assert.Equal(t, []string{
+		"example.com/broken: failed to install dependency Broken: not found",
+		"Installed: missing dependency Broken after installing it",
+	}, g.summary)
+}
@@ -0,0 +1,193 @@
+package updater
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/toc"
+)
+
+// TOC fields of the project IDs of sources without support
+var unsupportedFields = []string{"X-Curse-Project-ID", "X-Wago-ID"}
+
+// TOC fields of the addon website
+var websiteFields = []string{"X-Website", "X-URL"}
+
+// ScanAddons returns the addons installed in the addon directories which are not in the config
+// for the retail and the classic section. The addons are identified by the source fields of
+// their TOC files, e.g. X-WoWI-ID, as short reference or URL.
+func (u *Updater) ScanAddons() ([]config.AddOn, []config.AddOn, error) {
+	retail, err := u.retail.scanAddons(u.sources, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	classic, err := u.classic.scanAddons(u.sources, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return retail, classic, nil
+}
+
+// ImportAddons returns the scanned addons like ScanAddons and tracks them with the latest
+// version of their source in the versions file, so the next update does not install them again.
+// The version of a TOC file is not used as it rarely matches the version of the source.
+func (u *Updater) ImportAddons() ([]config.AddOn, []config.AddOn, error) {
+	retail, err := u.retail.scanAddons(u.sources, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	classic, err := u.classic.scanAddons(u.sources, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return retail, classic, saveVersionsFile(u)
+}
+
+// scanAddons returns the addons of the addon directory not in the config. Addons with several
+// folders are returned once. If track is set their latest versions are tracked.
+func (g *gameUpdater) scanAddons(sources *Registry, track bool) ([]config.AddOn, error) {
+	if g.config.Path == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(g.config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, addon := range g.config.AddOns {
+		if key, err := getAddonKey(sources, addon); err == nil {
+			known[key] = true
+		}
+	}
+
+	addons := make([]config.AddOn, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		f, err := toc.Read(filepath.Join(g.config.Path, entry.Name()), g.flavor)
+		if err != nil {
+			continue
+		}
+
+		ref, ok := tocReference(f, g.flavor, sources)
+		if !ok {
+			log.Printf("skipping %s: %s\n", entry.Name(), unidentifiedReason(f))
+			continue
+		}
+
+		url, err := sources.Expand(ref)
+		if err != nil {
+			log.Printf("skipping %s: %v\n", entry.Name(), err)
+			continue
+		}
+		key, err := getAddonKey(sources, config.AddOn{URL: url})
+		if err != nil {
+			log.Printf("skipping %s: %v\n", entry.Name(), err)
+			continue
+		}
+		if known[key] {
+			continue
+		}
+		known[key] = true
+
+		log.Printf("found %s in %s\n", ref, entry.Name())
+		addons = append(addons, config.AddOn{URL: ref})
+		if !track {
+			continue
+		}
+		if err = g.trackAddon(sources, url); err != nil {
+			log.Printf("failed to track the version of %s, it is installed again by the next update: %v\n", ref, err)
+		}
+	}
+
+	return addons, nil
+}
+
+// trackAddon tracks the latest version of the source for the addon under the key the update uses.
+// An addon tracked already keeps its version.
+func (g *gameUpdater) trackAddon(sources *Registry, addonURL string) error {
+	_, source, err := getSource(sources, config.AddOn{URL: addonURL})
+	if err != nil {
+		return err
+	}
+	if flavorSource, ok := source.(FlavorSource); ok {
+		source = flavorSource.ForFlavor(g.flavor)
+	}
+
+	key, err := addonKey(source, addonURL)
+	if err != nil || g.getCurrentVersion(key) != "" {
+		return err
+	}
+
+	version, err := source.GetLatestVersion(addonURL)
+	if err != nil {
+		return err
+	}
+	g.setCurrentVersion(key, addonURL, version)
+
+	return nil
+}
+
+// getAddonKey returns the key of the addon in the versions file
+func getAddonKey(sources *Registry, addon config.AddOn) (string, error) {
+	_, source, err := getSource(sources, addon)
+	if err != nil {
+		return "", err
+	}
+
+	return addonKey(source, addon.URL)
+}
+
+// tocReference returns the short reference or the URL of the addon from the source fields of its TOC file.
+// Websites are used if they are matched by a source identifying its addons, e.g. a GitHub repository.
+func tocReference(f *toc.File, flavor game.Flavor, sources *Registry) (string, bool) {
+	if id := f.Field("X-WoWI-ID"); id != "" {
+		return "wowi:" + id, true
+	}
+
+	if id := f.Field("X-Tukui-ProjectID"); id != "" {
+		switch id {
+		case "-1":
+			return "tukui:tukui", true
+		case "-2":
+			return "tukui:elvui", true
+		}
+		if flavor == game.Retail {
+			return "tukui:" + id, true
+		}
+		return "tukui:" + string(flavor) + ":" + id, true
+	}
+
+	for _, field := range websiteFields {
+		website := f.Field(field)
+		if website == "" {
+			continue
+		}
+		if _, source, err := sources.Find(website); err == nil {
+			if _, ok := source.(Identifier); ok {
+				return website, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// unidentifiedReason returns why the source of the addon is unknown
+func unidentifiedReason(f *toc.File) string {
+	for _, field := range unsupportedFields {
+		if id := f.Field(field); id != "" {
+			return "no source supports the " + field + " " + id
+		}
+	}
+
+	return "no source field in the toc file, add the addon to the config by hand"
+}
@@ -0,0 +1,175 @@
+package updater
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/updater/mocks"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+// site is a source identifying its addons with short references of the given prefix
+type site struct {
+	*mocks.MockUpdateSource
+	prefix string
+}
+
+func (s site) ReferencePrefix() string {
+	return s.prefix
+}
+
+func (s site) ExpandReference(ref string) (string, error) {
+	return s.prefix + ".example.com/" + ref, nil
+}
+
+func (s site) GetAddonID(addonURL string) (string, error) {
+	return strings.TrimPrefix(addonURL, s.prefix+".example.com/"), nil
+}
+
+// versionedSource returns a mock source of the given latest version
+func versionedSource(regex, version string) *mocks.MockUpdateSource {
+	m := mockSource(regex)
+	m.On("GetLatestVersion", mock.Anything).Return(version, nil)
+
+	return m
+}
+
+func newImportRegistry(t *testing.T) *Registry {
+	return newRegistry(t,
+		site{versionedSource(`^wowi\.example\.com/.+`, "2.0"), "wowi"},
+		site{versionedSource(`^tukui\.example\.com/.+`, "13.0"), "tukui"},
+		identifier{versionedSource(`^github\.com/.+`, "v2")},
+		versionedSource(`^plain\.com/.+`, "1"),
+	)
+}
+
+func writeInstalledAddons(t *testing.T, dir string) {
+	writeAddonFiles(t, dir, map[string]string{
+		"Configured/Configured.toc":         "## X-WoWI-ID: 100\n",
+		"Curse/Curse.toc":                   "## X-Curse-Project-ID: 1\n",
+		"ElvUI/ElvUI.toc":                   "## X-Tukui-ProjectID: -2\n## Version: 12.0\n",
+		"Hekili/Hekili.toc":                 "## X-WoWI-ID: 24608\n## Version: 1.0\n",
+		"Hekili_Options/Hekili_Options.toc": "## X-WoWI-ID: 24608\n## Version: 1.0\n",
+		"NoTOC/Core.lua":                    "",
+		"Plain/Plain.toc":                   "## X-Website: plain.com/addon\n",
+		"Plugin/Plugin.toc":                 "## X-Tukui-ProjectID: 42\n",
+		"Repo/Repo.toc":                     "## X-Website: github.com/owner/repo\n## Version: v1\n",
+	})
+}
+
+func Test_scanAddons(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	writeInstalledAddons(t, dir)
+	sources := newImportRegistry(t)
+	newUpdater := func(flavor game.Flavor) *gameUpdater {
+		return &gameUpdater{
+			config: config.WowConfig{
+				Path:   dir,
+				AddOns: []config.AddOn{{URL: "wowi.example.com/100"}},
+			},
+			flavor:   flavor,
+			versions: map[string]addon{},
+		}
+	}
+
+	t.Run("scan", func(t *testing.T) {
+		g := newUpdater(game.Retail)
+
+		addons, err := g.scanAddons(sources, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []config.AddOn{
+			{URL: "tukui:elvui"},
+			{URL: "wowi:24608"},
+			{URL: "tukui:42"},
+			{URL: "github.com/owner/repo"},
+		}, addons)
+		assert.Empty(t, g.versions)
+	})
+	t.Run("track versions", func(t *testing.T) {
+		g := newUpdater(game.Retail)
+		g.versions["repo"] = addon{Name: "repo", URL: "github.com/owner/repo", Version: "v1"}
+
+		_, err := g.scanAddons(sources, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]addon{
+			"24608": {Name: "24608", URL: "wowi.example.com/24608", Version: "2.0"},
+			"42":    {Name: "42", URL: "tukui.example.com/42", Version: "13.0"},
+			"elvui": {Name: "elvui", URL: "tukui.example.com/elvui", Version: "13.0"},
+			"repo":  {Name: "repo", URL: "github.com/owner/repo", Version: "v1"},
+		}, g.versions)
+	})
+	t.Run("classic flavor", func(t *testing.T) {
+		g := newUpdater(game.ClassicTBC)
+
+		addons, err := g.scanAddons(sources, false)
+
+		assert.NoError(t, err)
+		assert.Contains(t, addons, config.AddOn{URL: "tukui:classic-tbc:42"})
+	})
+	t.Run("no path", func(t *testing.T) {
+		addons, err := (&gameUpdater{}).scanAddons(sources, false)
+
+		assert.NoError(t, err)
+		assert.Empty(t, addons)
+	})
+	t.Run("missing path", func(t *testing.T) {
+		g := newUpdater(game.Retail)
+		g.config.Path = filepath.Join(dir, "missing")
+
+		_, err := g.scanAddons(sources, false)
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_ScanAddons(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	addonDir := filepath.Join(dir, "AddOns")
+	writeInstalledAddons(t, addonDir)
+	versionFile := filepath.Join(dir, ".versions")
+	conf := config.Config{
+		Retail: config.WowConfig{Path: addonDir, Compatibility: config.Ignore},
+	}
+	u, err := NewUpdater(conf, newImportRegistry(t), versionFile)
+	assert.NoError(t, err)
+
+	retail, classic, err := u.ScanAddons()
+
+	assert.NoError(t, err)
+	assert.Len(t, retail, 5)
+	assert.Empty(t, classic)
+	assert.NoFileExists(t, versionFile)
+}
+
+func Test_ImportAddons(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	addonDir := filepath.Join(dir, "AddOns")
+	writeInstalledAddons(t, addonDir)
+	versionFile := filepath.Join(dir, ".versions")
+	conf := config.Config{
+		Retail: config.WowConfig{Path: addonDir, Compatibility: config.Ignore},
+	}
+	u, err := NewUpdater(conf, newImportRegistry(t), versionFile)
+	assert.NoError(t, err)
+
+	retail, classic, err := u.ImportAddons()
+
+	assert.NoError(t, err)
+	assert.Len(t, retail, 5)
+	assert.Empty(t, classic)
+	vers, err := readVersionsFile(versionFile)
+	assert.NoError(t, err)
+	assert.Len(t, vers.Retail, 5)
+	assert.Contains(t, vers.Retail, addon{Name: "24608", URL: "wowi.example.com/24608", Version: "2.0"})
+}
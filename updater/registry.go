@@ -0,0 +1,186 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/updater/sources"
+)
+
+// Registry contains the available update sources by their name
+// in the order they are looked up for an addon URL.
+type Registry struct {
+	names   []string
+	sources map[string]UpdateSource
+}
+
+// NewRegistry returns a pointer to a newly created, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		names:   make([]string, 0),
+		sources: make(map[string]UpdateSource),
+	}
+}
+
+// Register adds the source with the given name to the end of the lookup order.
+// An ExternalSource fetches the externals of its addons via the registry.
+// Returns an error if the name is empty or already taken.
+func (r *Registry) Register(name string, source UpdateSource) error {
+	if name == "" {
+		return fmt.Errorf("a source requires a name")
+	}
+	if _, ok := r.sources[name]; ok {
+		return fmt.Errorf("a source with the name %s already exists", name)
+	}
+
+	r.names = append(r.names, name)
+	r.sources[name] = source
+	if externalSource, ok := source.(ExternalSource); ok {
+		externalSource.SetExternalFetcher(r.FetchExternal)
+	}
+	return nil
+}
+
+// Configure moves the sources of the given order to the front of the lookup order
+// and removes and closes the disabled sources.
+// Returns an error for unknown source names.
+func (r *Registry) Configure(order, disabled []string) error {
+	for _, name := range append(append([]string{}, order...), disabled...) {
+		if _, ok := r.sources[name]; !ok {
+			return fmt.Errorf("unknown source %s. available sources: %s", name, strings.Join(r.names, ", "))
+		}
+	}
+
+	names := make([]string, 0, len(r.names))
+	seen := make(map[string]bool, len(r.names))
+	for _, name := range append(append([]string{}, order...), r.names...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	closing := make([]UpdateSource, 0, len(disabled))
+	for _, name := range disabled {
+		source, ok := r.sources[name]
+		if !ok {
+			continue
+		}
+		names = remove(names, name)
+		delete(r.sources, name)
+		closing = append(closing, source)
+	}
+	r.names = names
+
+	// the disabled sources are removed even if closing one of them fails
+	var closeErr error
+	for _, source := range closing {
+		if err := source.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+
+	return closeErr
+}
+
+// Names returns the names of the sources in lookup order.
+func (r *Registry) Names() []string {
+	return append([]string{}, r.names...)
+}
+
+// Get returns the source of the given name.
+func (r *Registry) Get(name string) (UpdateSource, bool) {
+	source, ok := r.sources[name]
+	return source, ok
+}
+
+// Matching returns the names of all sources handling the given addon URL in lookup order.
+func (r *Registry) Matching(addonURL string) []string {
+	names := make([]string, 0)
+	for _, name := range r.names {
+		if r.sources[name].GetURLRegex().MatchString(addonURL) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Find returns the name and the first source in lookup order handling the given addon URL.
+func (r *Registry) Find(addonURL string) (string, UpdateSource, error) {
+	for _, name := range r.names {
+		source := r.sources[name]
+		if source.GetURLRegex().MatchString(addonURL) {
+			return name, source, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("addon url: %s is not supported", addonURL)
+}
+
+// Expand returns the addon URL for a short reference like gh:owner/repo of a source
+// implementing the ReferenceExpander. Any other addon URL is returned as it is.
+func (r *Registry) Expand(addonURL string) (string, error) {
+	parts := strings.SplitN(addonURL, ":", 2)
+	if len(parts) != 2 {
+		return addonURL, nil
+	}
+
+	for _, name := range r.names {
+		expander, ok := r.sources[name].(ReferenceExpander)
+		if !ok || expander.ReferencePrefix() != parts[0] {
+			continue
+		}
+
+		url, err := expander.ExpandReference(parts[1])
+		if err != nil {
+			return "", fmt.Errorf("invalid %s reference %s: %v", name, addonURL, err)
+		}
+
+		return url, nil
+	}
+
+	return addonURL, nil
+}
+
+// FetchExternal fetches the .pkgmeta external of the given URL with the first ExternalSource
+// in lookup order supporting it. Returns sources.ErrUnsupportedExternal if none supports it.
+func (r *Registry) FetchExternal(url, tag, dest string) error {
+	for _, name := range r.names {
+		externalSource, ok := r.sources[name].(ExternalSource)
+		if !ok {
+			continue
+		}
+
+		err := externalSource.FetchExternal(url, tag, dest)
+		if !errors.Is(err, sources.ErrUnsupportedExternal) {
+			return err
+		}
+	}
+
+	return sources.ErrUnsupportedExternal
+}
+
+// Close closes all sources. Returns the first error.
+func (r *Registry) Close() error {
+	var err error
+	for _, name := range r.names {
+		if closeErr := r.sources[name].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func remove(names []string, name string) []string {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i], names[i+1:]...)
+		}
+	}
+
+	return names
+}
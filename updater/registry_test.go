@@ -0,0 +1,224 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/updater/mocks"
+	"github.com/unly/wow-addon-updater/updater/sources"
+)
+
+// newRegistry returns a registry with the given sources named source0, source1, ...
+func newRegistry(t *testing.T, sources ...UpdateSource) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	for i, source := range sources {
+		if err := r.Register(fmt.Sprintf("source%d", i), source); err != nil {
+			assert.FailNow(t, "failed to register source", err)
+		}
+	}
+
+	return r
+}
+
+func mockSource(regex string) *mocks.MockUpdateSource {
+	m := &mocks.MockUpdateSource{}
+	m.On("GetURLRegex").Return(regexp.MustCompile(regex))
+	m.On("Close").Return(nil)
+
+	return m
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+
+	assert.NoError(t, r.Register("a", mockSource(".+")))
+	assert.Error(t, r.Register("a", mockSource(".+")))
+	assert.Error(t, r.Register("", mockSource(".+")))
+	assert.Equal(t, []string{"a"}, r.Names())
+}
+
+func TestRegistry_Configure(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    []string
+		disabled []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "registration order",
+			want: []string{"source0", "source1", "source2"},
+		},
+		{
+			name:  "ordered sources first",
+			order: []string{"source2", "source1"},
+			want:  []string{"source2", "source1", "source0"},
+		},
+		{
+			name:     "disabled sources",
+			order:    []string{"source2"},
+			disabled: []string{"source0"},
+			want:     []string{"source2", "source1"},
+		},
+		{
+			name:    "unknown ordered source",
+			order:   []string{"unknown"},
+			wantErr: true,
+		},
+		{
+			name:     "unknown disabled source",
+			disabled: []string{"unknown"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mockSource(".+")
+			r := newRegistry(t, m, mockSource(".+"), mockSource(".+"))
+
+			err := r.Configure(tt.order, tt.disabled)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, r.Names())
+				if len(tt.disabled) > 0 {
+					m.AssertCalled(t, "Close")
+					_, ok := r.Get("source0")
+					assert.False(t, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_Configure_CloseError(t *testing.T) {
+	failing := &mocks.MockUpdateSource{}
+	failing.On("GetURLRegex").Return(regexp.MustCompile(".+"))
+	failing.On("Close").Return(errors.New("close failed"))
+	other := mockSource(".+")
+	r := newRegistry(t, failing, other, mockSource(".+"))
+
+	err := r.Configure(nil, []string{"source0", "source1"})
+
+	assert.Error(t, err)
+	other.AssertCalled(t, "Close")
+	assert.Equal(t, []string{"source2"}, r.Names())
+	assert.NoError(t, r.Close())
+}
+
+func TestRegistry_Matching(t *testing.T) {
+	r := newRegistry(t, mockSource("test.com/.+"), mockSource("example.com/.+"), mockSource(".+"))
+
+	assert.Equal(t, []string{"source1", "source2"}, r.Matching("example.com/addon"))
+	assert.Equal(t, []string{"source2"}, r.Matching("other.com/addon"))
+
+	name, source, err := r.Find("example.com/addon")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "source1", name)
+	want, _ := r.Get("source1")
+	assert.Equal(t, want, source)
+}
+
+// expander is a source supporting short references with the prefix ex
+type expander struct {
+	*mocks.MockUpdateSource
+}
+
+func (expander) ReferencePrefix() string {
+	return "ex"
+}
+
+func (expander) ExpandReference(ref string) (string, error) {
+	if ref == "" {
+		return "", errors.New("empty reference")
+	}
+
+	return "example.com/" + ref, nil
+}
+
+func TestRegistry_Expand(t *testing.T) {
+	r := newRegistry(t, mockSource(".+"), expander{mockSource("example.com/.+")})
+
+	tests := []struct {
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{addonURL: "ex:addon", want: "example.com/addon"},
+		{addonURL: "ex:", wantErr: true},
+		{addonURL: "https://example.com/addon", want: "https://example.com/addon"},
+		{addonURL: "other:addon", want: "other:addon"},
+		{addonURL: "addon", want: "addon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			got, err := r.Expand(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+// externalSource is a source fetching the externals hosted on its host
+type externalSource struct {
+	*mocks.MockUpdateSource
+	host    string
+	fetched []string
+	fetch   sources.ExternalFetcher
+}
+
+func (e *externalSource) FetchExternal(url, tag, dest string) error {
+	if !strings.HasPrefix(url, e.host) {
+		return sources.ErrUnsupportedExternal
+	}
+	e.fetched = append(e.fetched, url)
+
+	return nil
+}
+
+func (e *externalSource) SetExternalFetcher(fetch sources.ExternalFetcher) {
+	e.fetch = fetch
+}
+
+func TestRegistry_FetchExternal(t *testing.T) {
+	first := &externalSource{MockUpdateSource: mockSource(".+"), host: "https://a.example.com/"}
+	second := &externalSource{MockUpdateSource: mockSource(".+"), host: "https://"}
+	r := newRegistry(t, first, mockSource(".+"), second)
+
+	assert.NotNil(t, first.fetch)
+	assert.NoError(t, first.fetch("https://a.example.com/lib", "", "dest"))
+	assert.NoError(t, r.FetchExternal("https://b.example.com/lib", "", "dest"))
+	assert.ErrorIs(t, r.FetchExternal("svn://example.com/lib", "", "dest"), sources.ErrUnsupportedExternal)
+	assert.Equal(t, []string{"https://a.example.com/lib"}, first.fetched)
+	assert.Equal(t, []string{"https://b.example.com/lib"}, second.fetched)
+}
+
+func TestRegistry_Close(t *testing.T) {
+	m1 := new(mocks.MockUpdateSource)
+	m1.On("Close").Return(errors.New("failed to close"))
+	m2 := new(mocks.MockUpdateSource)
+	m2.On("Close").Return(nil)
+	r := newRegistry(t, m1, m2)
+
+	err := r.Close()
+
+	assert.Error(t, err)
+	m1.AssertNumberOfCalls(t, "Close", 1)
+	m2.AssertNumberOfCalls(t, "Close", 1)
+}
@@ -0,0 +1,108 @@
+package direct
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/updater/sources"
+	"github.com/unly/wow-addon-updater/util"
+)
+
+var (
+	regex = regexp.MustCompile(`^https?://[^\s?#]+\.zip([?#]\S*)?$`)
+)
+
+// source is the source for plain zip archives on any web server
+type source struct {
+	downloader sources.Downloader
+	client     *http.Client
+	// archives already downloaded to compute their hash, by their URL
+	archives map[string]string
+}
+
+// New returns a new update source for direct links to zip archives.
+// It matches any http(s) URL ending with .zip and should be the last source to look up.
+func New(client *http.Client) (updater.UpdateSource, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	d, err := sources.NewDownloader(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &source{
+		downloader: d,
+		client:     client,
+		archives:   make(map[string]string),
+	}, nil
+}
+
+func (source) GetURLRegex() *regexp.Regexp {
+	return regex
+}
+
+// GetAddonID returns the host and the path of the archive URL, e.g. example.com/addon.zip
+func (source) GetAddonID(addonURL string) (string, error) {
+	return sources.URLID(addonURL)
+}
+
+// GetLatestVersion returns the ETag or Last-Modified header of the archive.
+// If the server sends neither, the archive is downloaded and its SHA-256 hash is used.
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	resp, err := s.client.Head(addonURL)
+	if util.CheckHTTPResponse(resp, err) == nil {
+		resp.Body.Close()
+		if etag := strings.Trim(strings.TrimPrefix(resp.Header.Get("ETag"), "W/"), `"`); etag != "" {
+			return etag, nil
+		}
+		if modified := resp.Header.Get("Last-Modified"); modified != "" {
+			return modified, nil
+		}
+	}
+
+	zipPath, err := s.download(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := util.HashFile(zipPath)
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hash, nil
+}
+
+// DownloadAddon downloads and unzip the archive to the given directory
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	zipPath, err := s.download(addonURL)
+	if err != nil {
+		return err
+	}
+
+	_, err = util.Unzip(zipPath, dir)
+	return err
+}
+
+// download downloads the archive once per run
+func (s *source) download(addonURL string) (string, error) {
+	if zipPath, ok := s.archives[addonURL]; ok {
+		return zipPath, nil
+	}
+
+	zipPath, err := s.downloader.DownloadZip(addonURL)
+	if err != nil {
+		return "", err
+	}
+	s.archives[addonURL] = zipPath
+
+	return zipPath, nil
+}
+
+func (s *source) Close() error {
+	return s.downloader.Close()
+}
@@ -0,0 +1,147 @@
+package direct
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func newDirectSource(t *testing.T) *source {
+	t.Helper()
+	s, err := New(nil)
+	if err != nil {
+		assert.FailNow(t, "failed to create direct source", err)
+	}
+
+	return s.(*source)
+}
+
+func serveArchive(t *testing.T, headers map[string]string) (*httptest.Server, *int) {
+	t.Helper()
+	downloads := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/addon.zip", func(w http.ResponseWriter, r *http.Request) {
+		for key, value := range headers {
+			w.Header().Set(key, value)
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		downloads++
+		content, err := os.ReadFile(filepath.Join("..", "_tests", "archive1.zip"))
+		assert.NoError(t, err)
+		_, _ = w.Write(content)
+	})
+
+	return httptest.NewServer(mux), &downloads
+}
+
+func Test_GetURLRegex(t *testing.T) {
+	tests := []struct {
+		addonURL string
+		want     bool
+	}{
+		{
+			addonURL: "https://example.com/addons/MyAddon.zip",
+			want:     true,
+		},
+		{
+			addonURL: "http://example.com/MyAddon-1.0.zip?download=1",
+			want:     true,
+		},
+		{
+			addonURL: "https://example.com/MyAddon.html",
+			want:     false,
+		},
+		{
+			addonURL: "ftp://example.com/MyAddon.zip",
+			want:     false,
+		},
+		{
+			addonURL: "https://example.com/?file=MyAddon.zip",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			actual := regex.MatchString(tt.addonURL)
+
+			assert.Equal(t, tt.want, actual)
+		})
+	}
+}
+
+func Test_GetLatestVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		headers       map[string]string
+		want          string
+		wantDownloads int
+	}{
+		{
+			name:    "etag",
+			headers: map[string]string{"ETag": `W/"abc123"`},
+			want:    "abc123",
+		},
+		{
+			name:    "last modified",
+			headers: map[string]string{"Last-Modified": "Wed, 21 Oct 2015 07:28:00 GMT"},
+			want:    "Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			name:          "content hash",
+			headers:       map[string]string{},
+			want:          "sha256:",
+			wantDownloads: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, downloads := serveArchive(t, tt.headers)
+			defer server.Close()
+			s := newDirectSource(t)
+			defer s.Close()
+
+			actual, err := s.GetLatestVersion(server.URL + "/addon.zip")
+
+			assert.NoError(t, err)
+			assert.Contains(t, actual, tt.want)
+			assert.Equal(t, tt.wantDownloads, *downloads)
+		})
+	}
+	t.Run("not found", func(t *testing.T) {
+		server, _ := serveArchive(t, nil)
+		defer server.Close()
+		s := newDirectSource(t)
+		defer s.Close()
+
+		_, err := s.GetLatestVersion(server.URL + "/missing.zip")
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_DownloadAddon(t *testing.T) {
+	server, downloads := serveArchive(t, nil)
+	defer server.Close()
+	s := newDirectSource(t)
+	defer s.Close()
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+
+	_, err := s.GetLatestVersion(server.URL + "/addon.zip")
+	assert.NoError(t, err)
+	err = s.DownloadAddon(server.URL+"/addon.zip", dir)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "root", "a.txt"))
+	assert.Equal(t, 1, *downloads)
+}
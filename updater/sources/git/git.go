@@ -0,0 +1,276 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/updater/sources"
+)
+
+var (
+	regex       = regexp.MustCompile(`^(git\+[a-z]+://\S+|git://\S+|ssh://\S+|[a-zA-Z0-9_.-]+@[a-zA-Z0-9_.-]+:\S+|https?://\S+\.git(#\S+)?)$`)
+	commitRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// source is the source for addons following a branch, tag or commit of a git repository
+type source struct {
+	sources.Externals
+
+	tempDir string
+	// git executable to run
+	git string
+}
+
+// New returns a new update source for git repositories. It requires git to be installed.
+// Supported are URLs with the git+ prefix, e.g. git+https:// or git+file://, the git://
+// and ssh:// protocols, scp-like URLs and http(s) URLs ending with .git.
+// The ref to follow is given as URL fragment, e.g. #main, and defaults to the remote HEAD.
+func New() (updater.UpdateSource, error) {
+	dir, err := os.MkdirTemp("", "wow-updater")
+	if err != nil {
+		return nil, err
+	}
+
+	return &source{
+		tempDir: dir,
+		git:     "git",
+	}, nil
+}
+
+func (source) GetURLRegex() *regexp.Regexp {
+	return regex
+}
+
+// GetAddonID returns the remote without .git suffix and the ref it follows,
+// e.g. git:https://example.com/owner/repo#main
+func (source) GetAddonID(addonURL string) (string, error) {
+	remote, ref := parseURL(addonURL)
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+
+	return "git:" + remote + "#" + ref, nil
+}
+
+// GetLatestVersion returns the commit hash the ref of the given repository URL points to.
+// Abbreviated commit hashes are resolved to the full hash.
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	remote, ref := parseURL(addonURL)
+	if commitRegex.MatchString(ref) {
+		return ref, nil
+	}
+
+	commit, err := s.lsRemote(remote, ref)
+	if err != nil || commit != "" {
+		return commit, err
+	}
+
+	repo, err := s.newRepository()
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(repo)
+
+	return s.fetchAll(repo, remote, ref)
+}
+
+// DownloadAddon fetches the ref of the given repository URL and installs its files
+// without the .git directory to the given directory.
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	remote, ref := parseURL(addonURL)
+	name := repositoryName(remote)
+
+	zipPath, err := s.archive(remote, ref, name)
+	if err != nil {
+		return err
+	}
+
+	return sources.InstallSourceArchive(zipPath, dir, name, s.Fetcher(s.FetchExternal))
+}
+
+// FetchExternal exports a .pkgmeta external git repository at the given tag or its HEAD.
+func (s *source) FetchExternal(url, tag, dest string) error {
+	if !regex.MatchString(url) {
+		return sources.ErrUnsupportedExternal
+	}
+
+	remote, ref := parseURL(url)
+	if tag != "" {
+		ref = tag
+	}
+
+	zipPath, err := s.archive(remote, ref, repositoryName(remote))
+	if err != nil {
+		return err
+	}
+
+	return sources.ExtractExternal(zipPath, dest)
+}
+
+// archive fetches the ref of the remote repository and returns the path of
+// a zip archive of its files within a single root directory of the given name.
+func (s *source) archive(remote, ref, name string) (string, error) {
+	repo, err := s.newRepository()
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(repo)
+
+	commit, err := s.fetch(repo, remote, ref)
+	if err != nil {
+		return "", err
+	}
+
+	zipPath := repo + ".zip"
+	_, err = s.run(repo, "archive", "--format=zip", "--prefix="+name+"/", "-o", zipPath, commit)
+	if err != nil {
+		return "", err
+	}
+
+	return zipPath, nil
+}
+
+// newRepository returns the path of a new empty repository in the temp directory
+func (s *source) newRepository() (string, error) {
+	repo, err := os.MkdirTemp(s.tempDir, "*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.run("", "init", "--quiet", repo)
+	if err != nil {
+		os.RemoveAll(repo)
+		return "", err
+	}
+
+	return repo, nil
+}
+
+// fetch fetches the ref of the remote repository into the given repository and returns its commit.
+// Full commit hashes and refs of the remote are fetched without history, anything else,
+// e.g. an abbreviated commit hash, is looked up in the fetched branches and tags.
+func (s *source) fetch(repo, remote, ref string) (string, error) {
+	if !commitRegex.MatchString(ref) {
+		commit, err := s.lsRemote(remote, ref)
+		if err != nil {
+			return "", err
+		}
+		if commit == "" {
+			return s.fetchAll(repo, remote, ref)
+		}
+	}
+
+	_, err := s.run(repo, "fetch", "--quiet", "--depth", "1", "--", remote, ref)
+	if err != nil {
+		return "", err
+	}
+
+	return s.revParse(repo, "FETCH_HEAD")
+}
+
+// fetchAll fetches all branches and tags of the remote repository into the given repository
+// and returns the commit of the ref
+func (s *source) fetchAll(repo, remote, ref string) (string, error) {
+	_, err := s.run(repo, "fetch", "--quiet", "--", remote, "+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*")
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := s.revParse(repo, ref)
+	if err != nil {
+		return "", fmt.Errorf("the ref %s does not exist in %s", ref, remote)
+	}
+
+	return commit, nil
+}
+
+// lsRemote returns the commit of the ref listed by the remote repository or an empty string
+// if the remote has no such branch or tag
+func (s *source) lsRemote(remote, ref string) (string, error) {
+	out, err := s.run("", "ls-remote", "--", remote, ref, ref+"^{}")
+	if err != nil {
+		return "", err
+	}
+
+	return findCommit(out, ref), nil
+}
+
+// revParse returns the full hash of the commit of the ref in the given repository
+func (s *source) revParse(repo, ref string) (string, error) {
+	out, err := s.run(repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (s *source) run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command(s.git, args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// parseURL returns the remote URL for git and the ref of the fragment, defaulting to HEAD.
+func parseURL(addonURL string) (string, string) {
+	remote := strings.TrimPrefix(addonURL, "git+")
+	ref := "HEAD"
+	if i := strings.LastIndex(remote, "#"); i >= 0 {
+		if remote[i+1:] != "" {
+			ref = remote[i+1:]
+		}
+		remote = remote[:i]
+	}
+
+	return remote, ref
+}
+
+// findCommit returns the commit of the ref from the ls-remote output, preferring
+// branches over tags. Annotated tags are resolved to the commit they point to.
+func findCommit(lsRemote []byte, ref string) string {
+	commits := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(lsRemote))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			commits[fields[1]] = fields[0]
+		}
+	}
+
+	candidates := []string{ref, "refs/heads/" + ref, "refs/tags/" + ref + "^{}", "refs/tags/" + ref}
+	for _, candidate := range candidates {
+		if commit, ok := commits[candidate]; ok {
+			return commit
+		}
+	}
+
+	return ""
+}
+
+// repositoryName returns the name of the repository without .git suffix
+func repositoryName(remote string) string {
+	name := path.Base(strings.TrimSuffix(filepath.ToSlash(remote), "/"))
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return strings.TrimSuffix(name, ".git")
+}
+
+func (s *source) Close() error {
+	return os.RemoveAll(s.tempDir)
+}
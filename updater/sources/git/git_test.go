@@ -0,0 +1,296 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func newGitSource(t *testing.T) *source {
+	t.Helper()
+	s, err := New()
+	if err != nil {
+		assert.FailNow(t, "failed to create git source", err)
+	}
+
+	return s.(*source)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		assert.FailNow(t, "git command failed", "%v: %s", err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// newBareRepository creates a bare repository Addon.git with a commit on main,
+// an annotated tag v1, a branch cafe with a hexadecimal name on the same commit
+// and a second commit on the branch dev.
+// Returns the repository path and the commits of main and dev.
+func newBareRepository(t *testing.T, dir string) (string, string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	bare := filepath.Join(dir, "Addon.git")
+	work := filepath.Join(dir, "work")
+	runGit(t, dir, "init", "--quiet", "--bare", bare)
+	runGit(t, dir, "init", "--quiet", work)
+	assert.NoError(t, os.WriteFile(filepath.Join(work, "Addon.toc"), []byte("## Version: 1\n"), os.FileMode(0666)))
+	assert.NoError(t, os.WriteFile(filepath.Join(work, ".gitignore"), []byte(""), os.FileMode(0666)))
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "commit", "--quiet", "-m", "initial")
+	runGit(t, work, "tag", "-a", "v1", "-m", "v1")
+	main := runGit(t, work, "rev-parse", "HEAD")
+	runGit(t, work, "checkout", "--quiet", "-b", "dev")
+	assert.NoError(t, os.WriteFile(filepath.Join(work, "Dev.lua"), []byte(""), os.FileMode(0666)))
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "commit", "--quiet", "-m", "dev")
+	dev := runGit(t, work, "rev-parse", "HEAD")
+	runGit(t, work, "push", "--quiet", "--tags", bare, "main", "dev", "main:refs/heads/cafe")
+
+	return bare, main, dev
+}
+
+func Test_GetURLRegex(t *testing.T) {
+	tests := []struct {
+		addonURL string
+		want     bool
+	}{
+		{
+			addonURL: "git+https://github.com/owner/addon#main",
+			want:     true,
+		},
+		{
+			addonURL: "git+file:///home/user/repos/Addon.git",
+			want:     true,
+		},
+		{
+			addonURL: "https://example.com/owner/addon.git#v1.0",
+			want:     true,
+		},
+		{
+			addonURL: "git@github.com:owner/addon.git",
+			want:     true,
+		},
+		{
+			addonURL: "ssh://git@example.com/addon.git",
+			want:     true,
+		},
+		{
+			addonURL: "https://github.com/owner/addon",
+			want:     false,
+		},
+		{
+			addonURL: "file:///home/user/addons/Addon",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			assert.Equal(t, tt.want, regex.MatchString(tt.addonURL))
+		})
+	}
+}
+
+func Test_parseURL(t *testing.T) {
+	tests := []struct {
+		addonURL   string
+		wantRemote string
+		wantRef    string
+		wantName   string
+		wantID     string
+	}{
+		{
+			addonURL:   "git+https://github.com/owner/addon#main",
+			wantRemote: "https://github.com/owner/addon",
+			wantRef:    "main",
+			wantName:   "addon",
+			wantID:     "git:https://github.com/owner/addon#main",
+		},
+		{
+			addonURL:   "git+file:///repos/Addon.git#",
+			wantRemote: "file:///repos/Addon.git",
+			wantRef:    "HEAD",
+			wantName:   "Addon",
+			wantID:     "git:file:///repos/Addon#HEAD",
+		},
+		{
+			addonURL:   "git@github.com:Addon.git",
+			wantRemote: "git@github.com:Addon.git",
+			wantRef:    "HEAD",
+			wantName:   "Addon",
+			wantID:     "git:git@github.com:Addon#HEAD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			remote, ref := parseURL(tt.addonURL)
+
+			assert.Equal(t, tt.wantRemote, remote)
+			assert.Equal(t, tt.wantRef, ref)
+			assert.Equal(t, tt.wantName, repositoryName(remote))
+			id, err := source{}.GetAddonID(tt.addonURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantID, id)
+		})
+	}
+}
+
+func Test_GetLatestVersion(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	bare, main, dev := newBareRepository(t, dir)
+	url := "git+file://" + filepath.ToSlash(bare)
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "remote head",
+			ref:  "",
+			want: main,
+		},
+		{
+			name: "branch",
+			ref:  "#dev",
+			want: dev,
+		},
+		{
+			name: "annotated tag",
+			ref:  "#v1",
+			want: main,
+		},
+		{
+			name: "hexadecimal branch",
+			ref:  "#cafe",
+			want: main,
+		},
+		{
+			name: "commit",
+			ref:  "#" + dev,
+			want: dev,
+		},
+		{
+			name: "abbreviated commit",
+			ref:  "#" + dev[:10],
+			want: dev,
+		},
+		{
+			name:    "unknown ref",
+			ref:     "#unknown",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newGitSource(t)
+			defer source.Close()
+
+			got, err := source.GetLatestVersion(url + tt.ref)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_DownloadAddon(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	bare, main, dev := newBareRepository(t, dir)
+	url := "git+file://" + filepath.ToSlash(bare)
+
+	t.Run("branch", func(t *testing.T) {
+		source := newGitSource(t)
+		defer source.Close()
+		addons := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, addons)()
+
+		err := source.DownloadAddon(url+"#dev", addons)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(addons, "Addon", "Addon.toc"))
+		assert.FileExists(t, filepath.Join(addons, "Addon", "Dev.lua"))
+		assert.NoFileExists(t, filepath.Join(addons, "Addon", ".gitignore"))
+		assert.NoDirExists(t, filepath.Join(addons, "Addon", ".git"))
+	})
+	t.Run("tag", func(t *testing.T) {
+		source := newGitSource(t)
+		defer source.Close()
+		addons := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, addons)()
+
+		err := source.DownloadAddon(url+"#v1", addons)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(addons, "Addon", "Addon.toc"))
+		assert.NoFileExists(t, filepath.Join(addons, "Addon", "Dev.lua"))
+	})
+	t.Run("commit", func(t *testing.T) {
+		source := newGitSource(t)
+		defer source.Close()
+		addons := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, addons)()
+
+		err := source.DownloadAddon(url+"#"+dev, addons)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(addons, "Addon", "Dev.lua"))
+	})
+	t.Run("abbreviated commit", func(t *testing.T) {
+		source := newGitSource(t)
+		defer source.Close()
+		addons := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, addons)()
+
+		err := source.DownloadAddon(url+"#"+main[:7], addons)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(addons, "Addon", "Addon.toc"))
+		assert.NoFileExists(t, filepath.Join(addons, "Addon", "Dev.lua"))
+	})
+	t.Run("unknown abbreviated commit", func(t *testing.T) {
+		source := newGitSource(t)
+		defer source.Close()
+		addons := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, addons)()
+
+		err := source.DownloadAddon(url+"#0000000", addons)
+
+		assert.Error(t, err)
+	})
+	t.Run("not existing repository", func(t *testing.T) {
+		source := newGitSource(t)
+		defer source.Close()
+		addons := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, addons)()
+
+		err := source.DownloadAddon("git+file://"+filepath.ToSlash(filepath.Join(dir, "missing.git")), addons)
+
+		assert.Error(t, err)
+	})
+}
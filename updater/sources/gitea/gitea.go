@@ -0,0 +1,200 @@
+package gitea
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/updater/sources"
+	"github.com/unly/wow-addon-updater/util"
+)
+
+const defaultURL = "https://codeberg.org"
+
+type release struct {
+	TagName    string `json:"tag_name"`
+	ZipballURL string `json:"zipball_url"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// source is the source for addons hosted on codeberg.org or self-hosted Gitea and Forgejo instances
+type source struct {
+	sources.Externals
+
+	downloader sources.Downloader
+	client     *http.Client
+	instances  sources.Instances
+	regex      *regexp.Regexp
+}
+
+// New returns a new update source for codeberg.org and the given self-hosted instances.
+// Access tokens of the instances are sent along with every request to the respective host.
+func New(client *http.Client, hosts []config.HostConfig) (updater.UpdateSource, error) {
+	instances, client, err := sources.NewInstances(client, defaultURL, hosts, func(token string) http.Header {
+		return http.Header{"Authorization": []string{"token " + token}}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitea: %w", err)
+	}
+
+	d, err := sources.NewDownloader(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &source{
+		downloader: d,
+		client:     client,
+		instances:  instances,
+		regex:      regexp.MustCompile(instances.Pattern() + `/[a-zA-Z0-9_.-]+/[a-zA-Z0-9_.-]+/?$`),
+	}, nil
+}
+
+func (s *source) GetURLRegex() *regexp.Regexp {
+	return s.regex
+}
+
+// GetAddonID returns the host, owner and name of the repository in lower case,
+// e.g. codeberg.org/owner/repo
+func (s *source) GetAddonID(addonURL string) (string, error) {
+	inst, owner, repo, err := s.getOwnerAndRepository(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(inst.Prefix + "/" + owner + "/" + repo), nil
+}
+
+// GetLatestVersion returns the tag of the latest release of the given repository URL.
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	inst, owner, repo, err := s.getOwnerAndRepository(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := s.getLatestRelease(inst, owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	return rel.TagName, nil
+}
+
+// DownloadAddon downloads and unzip the first .zip attachment of the latest release.
+// Otherwise the archive of the release tag will be downloaded and installed
+// to the given directory.
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	inst, owner, repo, err := s.getOwnerAndRepository(addonURL)
+	if err != nil {
+		return err
+	}
+
+	rel, err := s.getLatestRelease(inst, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	// approach to download an attachment rather than the entire repository
+	if assetURL := rel.zipAsset(); assetURL != "" {
+		zipPath, err := s.downloader.DownloadZip(assetURL)
+		if err != nil {
+			return err
+		}
+
+		_, err = util.Unzip(zipPath, dir)
+		return err
+	}
+
+	archive := rel.ZipballURL
+	if archive == "" {
+		archive = archiveURL(inst, owner, repo, rel.TagName)
+	}
+
+	zipPath, err := s.downloader.DownloadZip(archive)
+	if err != nil {
+		return err
+	}
+
+	return sources.InstallSourceArchive(zipPath, dir, repo, s.Fetcher(s.FetchExternal))
+}
+
+// zipAsset returns the download URL of the first .zip attachment of the release
+func (r *release) zipAsset() string {
+	for _, asset := range r.Assets {
+		if strings.HasSuffix(strings.ToLower(asset.Name), ".zip") {
+			return asset.BrowserDownloadURL
+		}
+	}
+
+	return ""
+}
+
+// FetchExternal downloads the archive of a .pkgmeta external hosted on one of
+// the instances at the given tag or the default branch.
+func (s *source) FetchExternal(externalURL, tag, dest string) error {
+	externalURL = strings.TrimSuffix(externalURL, ".git")
+	if !s.regex.MatchString(externalURL) {
+		return sources.ErrUnsupportedExternal
+	}
+
+	inst, owner, repo, err := s.getOwnerAndRepository(externalURL)
+	if err != nil {
+		return err
+	}
+
+	if tag == "" {
+		tag = "HEAD"
+	}
+
+	zipPath, err := s.downloader.DownloadZip(archiveURL(inst, owner, repo, tag))
+	if err != nil {
+		return err
+	}
+
+	return sources.ExtractExternal(zipPath, dest)
+}
+
+func (s *source) getLatestRelease(inst sources.Instance, owner, repo string) (*release, error) {
+	var rel release
+	err := util.GetJSON(s.client, fmt.Sprintf("%s/api/v1/repos/%s/%s/releases/latest", inst.URL, owner, repo), &rel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rel, nil
+}
+
+// getOwnerAndRepository returns the instance hosting the repository, its owner and name.
+func (s *source) getOwnerAndRepository(addonURL string) (sources.Instance, string, string, error) {
+	// the clone URL of the repository ends with .git
+	trimmed := strings.TrimSuffix(sources.TrimURL(addonURL), ".git")
+
+	for _, inst := range s.instances {
+		repo := strings.TrimPrefix(trimmed, inst.Prefix+"/")
+		if repo == trimmed {
+			continue
+		}
+
+		split := strings.Split(repo, "/")
+		if len(split) == 2 && split[0] != "" && split[1] != "" {
+			return inst, split[0], split[1], nil
+		}
+	}
+
+	return sources.Instance{}, "", "", fmt.Errorf("the given url %s is invalid for a gitea repository", addonURL)
+}
+
+func archiveURL(inst sources.Instance, owner, repo, ref string) string {
+	return fmt.Sprintf("%s/%s/%s/archive/%s.zip", inst.URL, owner, repo, url.PathEscape(ref))
+}
+
+func (s *source) Close() error {
+	return s.downloader.Close()
+}
@@ -0,0 +1,277 @@
+package gitea
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func newGiteaSource(t *testing.T, hosts ...config.HostConfig) *source {
+	t.Helper()
+	s, err := New(nil, hosts)
+	if err != nil {
+		assert.FailNow(t, "failed to create gitea source", err)
+	}
+
+	return s.(*source)
+}
+
+// newGiteaServer returns a test server answering the given paths with the given responses.
+// Responses ending with .zip are served from the file system, {{server}} is replaced with the server URL.
+func newGiteaServer(t *testing.T, responses map[string]string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if filepath.Ext(response) == ".zip" {
+			content, err := os.ReadFile(response)
+			assert.NoError(t, err)
+			_, _ = w.Write(content)
+			return
+		}
+		_, _ = w.Write([]byte(strings.ReplaceAll(response, "{{server}}", server.URL)))
+	}))
+
+	return server
+}
+
+func Test_GetURLRegex(t *testing.T) {
+	s := newGiteaSource(t, config.HostConfig{URL: "https://git.example.com"})
+	defer s.Close()
+
+	tests := []struct {
+		addonURL string
+		want     bool
+	}{
+		{
+			addonURL: "https://codeberg.org/owner/addon",
+			want:     true,
+		},
+		{
+			addonURL: "codeberg.org/owner/addon/",
+			want:     true,
+		},
+		{
+			addonURL: "https://git.example.com/owner/addon",
+			want:     true,
+		},
+		{
+			addonURL: "https://codeberg.org/owner",
+			want:     false,
+		},
+		{
+			addonURL: "https://codeberg.org/owner/addon/releases",
+			want:     false,
+		},
+		{
+			addonURL: "https://gitlab.com/owner/addon",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			actual := s.GetURLRegex().MatchString(tt.addonURL)
+
+			assert.Equal(t, tt.want, actual)
+		})
+	}
+}
+
+func Test_getOwnerAndRepository(t *testing.T) {
+	s := newGiteaSource(t, config.HostConfig{URL: "https://example.com/gitea/", Token: "secret"})
+	defer s.Close()
+
+	tests := []struct {
+		addonURL  string
+		wantBase  string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{
+			addonURL:  "https://codeberg.org/owner/addon/",
+			wantBase:  "https://codeberg.org",
+			wantOwner: "owner",
+			wantRepo:  "addon",
+		},
+		{
+			addonURL:  "example.com/gitea/owner/addon",
+			wantBase:  "https://example.com/gitea",
+			wantOwner: "owner",
+			wantRepo:  "addon",
+		},
+		{
+			addonURL: "https://codeberg.org/owner",
+			wantErr:  true,
+		},
+		{
+			addonURL: "https://example.com/owner/addon",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			inst, owner, repo, err := s.getOwnerAndRepository(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantBase, inst.URL)
+				assert.Equal(t, tt.wantOwner, owner)
+				assert.Equal(t, tt.wantRepo, repo)
+			}
+		})
+	}
+}
+
+func Test_GetAddonID(t *testing.T) {
+	s := newGiteaSource(t, config.HostConfig{URL: "https://example.com/gitea/"})
+	defer s.Close()
+
+	tests := []struct {
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{addonURL: "https://codeberg.org/Owner/Addon/", want: "codeberg.org/owner/addon"},
+		{addonURL: "http://www.codeberg.org/owner/addon", want: "codeberg.org/owner/addon"},
+		{addonURL: "https://codeberg.org/owner/addon.git", want: "codeberg.org/owner/addon"},
+		{addonURL: "example.com/gitea/owner/addon", want: "example.com/gitea/owner/addon"},
+		{addonURL: "https://codeberg.org/owner", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			got, err := s.GetAddonID(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_GetLatestVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "latest release",
+			responses: map[string]string{
+				"/api/v1/repos/owner/addon/releases/latest": `{"tag_name": "v1.2.3"}`,
+			},
+			want: "v1.2.3",
+		},
+		{
+			name:      "no release",
+			responses: map[string]string{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newGiteaServer(t, tt.responses)
+			defer server.Close()
+			s := newGiteaSource(t, config.HostConfig{URL: server.URL, Token: "secret"})
+			defer s.Close()
+
+			actual, err := s.GetLatestVersion(server.URL + "/owner/addon")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, actual)
+			}
+		})
+	}
+}
+
+func Test_DownloadAddon(t *testing.T) {
+	archive := filepath.Join("..", "_tests", "archive1.zip")
+
+	tests := []struct {
+		name      string
+		responses map[string]string
+		wantFile  string
+		wantErr   bool
+	}{
+		{
+			name: "release attachment",
+			responses: map[string]string{
+				"/api/v1/repos/owner/addon/releases/latest": `{"tag_name": "v1", "assets": [
+					{"name": "notes.txt", "browser_download_url": "{{server}}/notes.txt"},
+					{"name": "addon-v1.zip", "browser_download_url": "{{server}}/attachments/1"}
+				]}`,
+				"/attachments/1": archive,
+			},
+			wantFile: filepath.Join("root", "a.txt"),
+		},
+		{
+			name: "release zipball",
+			responses: map[string]string{
+				"/api/v1/repos/owner/addon/releases/latest": `{"tag_name": "v1", "zipball_url": "{{server}}/owner/addon/archive/v1.zip"}`,
+				"/owner/addon/archive/v1.zip":               archive,
+			},
+			wantFile: filepath.Join("addon", "a.txt"),
+		},
+		{
+			name: "tag archive",
+			responses: map[string]string{
+				"/api/v1/repos/owner/addon/releases/latest": `{"tag_name": "v2"}`,
+				"/owner/addon/archive/v2.zip":               archive,
+			},
+			wantFile: filepath.Join("addon", "a.txt"),
+		},
+		{
+			name: "failing download",
+			responses: map[string]string{
+				"/api/v1/repos/owner/addon/releases/latest": `{"tag_name": "v1"}`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newGiteaServer(t, tt.responses)
+			defer server.Close()
+			s := newGiteaSource(t, config.HostConfig{URL: server.URL, Token: "secret"})
+			defer s.Close()
+			dir := helpers.TempDir(t)
+			defer helpers.DeleteDir(t, dir)()
+
+			err := s.DownloadAddon(server.URL+"/owner/addon", dir)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.FileExists(t, filepath.Join(dir, tt.wantFile))
+			}
+		})
+	}
+}
@@ -3,10 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
-	"math"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -29,9 +26,12 @@ type githubAPI interface {
 }
 
 type githubSource struct {
+	sources.Externals
+
 	downloader sources.Downloader
 	client     *http.Client
 	api        githubAPI
+	apiURL     string
 }
 
 // New returns a pointer to a newly created GithubSource.
@@ -44,10 +44,13 @@ func New(client *http.Client) (updater.UpdateSource, error) {
 		return nil, err
 	}
 
+	ghClient := github.NewClient(client)
+
 	return &githubSource{
 		downloader: d,
 		client:     client,
-		api:        github.NewClient(client).Repositories,
+		api:        ghClient.Repositories,
+		apiURL:     ghClient.BaseURL.String(),
 	}, nil
 }
 
@@ -55,6 +58,30 @@ func (githubSource) GetURLRegex() *regexp.Regexp {
 	return regex
 }
 
+// GetAddonID returns the repository of the URL in lower case, e.g. github.com/owner/repo
+func (g *githubSource) GetAddonID(addonURL string) (string, error) {
+	owner, repo, err := g.getOrgAndRepository(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower("github.com/" + owner + "/" + repo), nil
+}
+
+func (githubSource) ReferencePrefix() string {
+	return "gh"
+}
+
+// ExpandReference returns the repository URL for a short reference of the form owner/repo
+func (githubSource) ExpandReference(ref string) (string, error) {
+	url := "https://github.com/" + ref
+	if strings.Count(ref, "/") != 1 || !regex.MatchString(url) {
+		return "", fmt.Errorf("expected owner/repo")
+	}
+
+	return url, nil
+}
+
 // GetLatestVersion returns the git tag of the latest release of the given repository URL.
 func (g *githubSource) GetLatestVersion(addonURL string) (string, error) {
 	release, err := g.getLatestRelease(addonURL)
@@ -68,7 +95,7 @@ func (g *githubSource) GetLatestVersion(addonURL string) (string, error) {
 // DownloadAddon downloads and unzip the addon if there is just one .zip archive attached
 // to the latest release.
 // Otherwise the git repository itself will be downloaded and copied to the given
-// directory.
+// directory, applying the packaging instructions of its .pkgmeta file.
 func (g *githubSource) DownloadAddon(addonURL, dir string) error {
 	release, err := g.getLatestRelease(addonURL)
 	if err != nil {
@@ -91,40 +118,38 @@ func (g *githubSource) DownloadAddon(addonURL, dir string) error {
 		return err
 	}
 
-	files, err := util.Unzip(zipPath, dir)
+	if !gitArchive {
+		_, err = util.Unzip(zipPath, dir)
+		return err
+	}
+
+	_, repo, err := g.getOrgAndRepository(addonURL)
 	if err != nil {
 		return err
 	}
 
-	// rename the git archive to the repository name
-	if gitArchive {
-		minLength := math.MaxInt32
-		dirs := make([]string, 0)
-		for _, f := range files {
-			length := len(f)
-			if length < minLength {
-				dirs = make([]string, 0)
-				minLength = length
-			}
-			if length == minLength {
-				dirs = append(dirs, f)
-			}
-		}
-		if len(dirs) != 1 {
-			return fmt.Errorf("the git archive does not have a single root directory")
-		}
-		_, repo, err := g.getOrgAndRepository(addonURL)
-		if err != nil {
-			return err
-		}
-		//TODO overwrite existing files
-		err = os.Rename(dirs[0], filepath.Join(dir, repo))
-		if err != nil {
-			return err
-		}
+	return sources.InstallSourceArchive(zipPath, dir, repo, g.Fetcher(g.FetchExternal))
+}
+
+// FetchExternal downloads the source archive of a .pkgmeta external hosted on github
+// at the given tag or the default branch.
+func (g *githubSource) FetchExternal(url, tag, dest string) error {
+	url = strings.TrimSuffix(url, ".git")
+	if !regex.MatchString(url) {
+		return sources.ErrUnsupportedExternal
+	}
+
+	owner, repo, err := g.getOrgAndRepository(url)
+	if err != nil {
+		return err
+	}
+
+	zipPath, err := g.downloader.DownloadZip(fmt.Sprintf("%srepos/%s/%s/zipball/%s", g.apiURL, owner, repo, tag))
+	if err != nil {
+		return err
 	}
 
-	return err
+	return sources.ExtractExternal(zipPath, dest)
 }
 
 func (g *githubSource) getLatestRelease(addonURL string) (*github.RepositoryRelease, error) {
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/unly/wow-addon-updater/updater/sources"
 	"github.com/unly/wow-addon-updater/updater/sources/github/mocks"
 	"github.com/unly/wow-addon-updater/util/tests/helpers"
 )
@@ -309,6 +310,60 @@ func Test_GetURLRegex(t *testing.T) {
 	}
 }
 
+func Test_GetAddonID(t *testing.T) {
+	source := newGitHubSource(t, nil)
+	defer source.Close()
+
+	tests := []struct {
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{addonURL: "https://github.com/Owner/Addon/", want: "github.com/owner/addon"},
+		{addonURL: "github.com/owner/addon", want: "github.com/owner/addon"},
+		{addonURL: "github.com/owner", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			got, err := source.GetAddonID(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_ExpandReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "owner/repo", want: "https://github.com/owner/repo"},
+		{ref: "owner", wantErr: true},
+		{ref: "owner/repo/releases", wantErr: true},
+		{ref: "owner/re po", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := githubSource{}.ExpandReference(tt.ref)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
 func Test_DownloadAddon(t *testing.T) {
 	type testStruct struct {
 		source        *githubSource
@@ -624,6 +679,86 @@ func Test_DownloadAddon(t *testing.T) {
 	}
 }
 
+func Test_DownloadAddon_ReplacesExistingFolder(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/download/addon", func(w http.ResponseWriter, r *http.Request) {
+		content, err := os.ReadFile(filepath.Join("..", "_tests", "archive1.zip"))
+		assert.NoError(t, err)
+		_, _ = w.Write(content)
+	})
+
+	source := newGitHubSource(t, nil)
+	defer source.Close()
+	m := &mocks.MockGitHubAPI{}
+	resp := &github.Response{
+		Response: &http.Response{
+			StatusCode: http.StatusOK,
+		},
+	}
+	release := &github.RepositoryRelease{
+		TagName:    stringPtr("1.2.3"),
+		ZipballURL: stringPtr(server.URL + "/download/addon"),
+	}
+	m.On("GetLatestRelease", mock.Anything, "owner", "addon").Return(release, resp, nil)
+	source.api = m
+
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	addonDir := filepath.Join(dir, "addon")
+	err := os.MkdirAll(addonDir, os.ModePerm)
+	if err != nil {
+		assert.FailNow(t, "failed to create the addon directory", err)
+	}
+	err = os.WriteFile(filepath.Join(addonDir, "stale.txt"), []byte{}, os.FileMode(0666))
+	if err != nil {
+		assert.FailNow(t, "failed to write stale file", err)
+	}
+
+	err = source.DownloadAddon("github.com/owner/addon", dir)
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(addonDir, "stale.txt"))
+	assert.FileExists(t, filepath.Join(addonDir, "a.txt"))
+	assert.NoDirExists(t, filepath.Join(dir, "root"))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_FetchExternal(t *testing.T) {
+	t.Run("unsupported external", func(t *testing.T) {
+		source := newGitHubSource(t, nil)
+		defer source.Close()
+
+		err := source.FetchExternal("https://repos.wowace.com/wow/libstub/trunk", "", "")
+
+		assert.ErrorIs(t, err, sources.ErrUnsupportedExternal)
+	})
+	t.Run("download tagged external", func(t *testing.T) {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+		defer server.Close()
+		mux.HandleFunc("/repos/owner/lib/zipball/v1", func(w http.ResponseWriter, r *http.Request) {
+			content, err := os.ReadFile(filepath.Join("..", "_tests", "archive1.zip"))
+			assert.NoError(t, err)
+			_, _ = w.Write(content)
+		})
+		source := newGitHubSource(t, nil)
+		defer source.Close()
+		source.apiURL = server.URL + "/"
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		dest := filepath.Join(dir, "Libs", "lib")
+
+		err := source.FetchExternal("https://github.com/owner/lib.git", "v1", dest)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dest, "a.txt"))
+	})
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,245 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/updater/sources"
+	"github.com/unly/wow-addon-updater/util"
+)
+
+const defaultURL = "https://gitlab.com"
+
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  struct {
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+// zipAsset returns the download URL of the first .zip asset linked to the release
+func (r *release) zipAsset() string {
+	for _, link := range r.Assets.Links {
+		if !strings.HasSuffix(strings.ToLower(link.Name), ".zip") {
+			continue
+		}
+		if link.DirectAssetURL != "" {
+			return link.DirectAssetURL
+		}
+		return link.URL
+	}
+
+	return ""
+}
+
+type tag struct {
+	Name string `json:"name"`
+}
+
+// source is the source for addons hosted on gitlab.com or self-hosted GitLab instances
+type source struct {
+	sources.Externals
+
+	downloader sources.Downloader
+	client     *http.Client
+	instances  sources.Instances
+	regex      *regexp.Regexp
+}
+
+// New returns a new update source for gitlab.com and the given self-hosted instances.
+// Access tokens of the instances are sent along with every request to the respective host.
+func New(client *http.Client, hosts []config.HostConfig) (updater.UpdateSource, error) {
+	instances, client, err := sources.NewInstances(client, defaultURL, hosts, func(token string) http.Header {
+		return http.Header{"Private-Token": []string{token}}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: %w", err)
+	}
+
+	d, err := sources.NewDownloader(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &source{
+		downloader: d,
+		client:     client,
+		instances:  instances,
+		regex:      regexp.MustCompile(instances.Pattern() + `(/[a-zA-Z0-9_][a-zA-Z0-9_.-]*){2,}/?$`),
+	}, nil
+}
+
+func (s *source) GetURLRegex() *regexp.Regexp {
+	return s.regex
+}
+
+// GetAddonID returns the host and the full path of the project in lower case,
+// e.g. gitlab.com/group/project
+func (s *source) GetAddonID(addonURL string) (string, error) {
+	inst, project, err := s.getProject(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(inst.Prefix + "/" + project), nil
+}
+
+// GetLatestVersion returns the tag of the latest release of the given project URL.
+// Falls back to the latest tag if the project does not have any releases.
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	inst, project, err := s.getProject(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := s.getLatestRelease(inst, project)
+	if err != nil {
+		return "", err
+	}
+	if rel != nil {
+		return rel.TagName, nil
+	}
+
+	return s.getLatestTag(inst, project)
+}
+
+// DownloadAddon downloads and unzip the first .zip asset of the latest release.
+// Otherwise the repository archive of the latest release or tag will be downloaded
+// and installed to the given directory.
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	inst, project, err := s.getProject(addonURL)
+	if err != nil {
+		return err
+	}
+
+	rel, err := s.getLatestRelease(inst, project)
+	if err != nil {
+		return err
+	}
+
+	ref := ""
+	if rel != nil {
+		ref = rel.TagName
+		// approach to download an asset rather than the entire repository
+		if assetURL := rel.zipAsset(); assetURL != "" {
+			zipPath, err := s.downloader.DownloadZip(assetURL)
+			if err != nil {
+				return err
+			}
+
+			_, err = util.Unzip(zipPath, dir)
+			return err
+		}
+	} else {
+		ref, err = s.getLatestTag(inst, project)
+		if err != nil {
+			return err
+		}
+	}
+
+	zipPath, err := s.downloader.DownloadZip(archiveURL(inst, project, ref))
+	if err != nil {
+		return err
+	}
+
+	return sources.InstallSourceArchive(zipPath, dir, path.Base(project), s.Fetcher(s.FetchExternal))
+}
+
+// FetchExternal downloads the repository archive of a .pkgmeta external hosted
+// on one of the GitLab instances at the given tag or the default branch.
+func (s *source) FetchExternal(externalURL, tag, dest string) error {
+	externalURL = strings.TrimSuffix(externalURL, ".git")
+	if !s.regex.MatchString(externalURL) {
+		return sources.ErrUnsupportedExternal
+	}
+
+	inst, project, err := s.getProject(externalURL)
+	if err != nil {
+		return err
+	}
+
+	zipPath, err := s.downloader.DownloadZip(archiveURL(inst, project, tag))
+	if err != nil {
+		return err
+	}
+
+	return sources.ExtractExternal(zipPath, dest)
+}
+
+// getLatestRelease returns the most recent release of the project or nil
+// if there is none.
+func (s *source) getLatestRelease(inst sources.Instance, project string) (*release, error) {
+	releases := make([]release, 0)
+	err := util.GetJSON(s.client, fmt.Sprintf("%s/projects/%s/releases?per_page=1", apiURL(inst), escape(project)), &releases)
+	if err != nil {
+		return nil, err
+	}
+	if len(releases) == 0 {
+		return nil, nil
+	}
+
+	return &releases[0], nil
+}
+
+func (s *source) getLatestTag(inst sources.Instance, project string) (string, error) {
+	tags := make([]tag, 0)
+	err := util.GetJSON(s.client, fmt.Sprintf("%s/projects/%s/repository/tags?per_page=1", apiURL(inst), escape(project)), &tags)
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "", errors.New("the project has neither releases nor tags")
+	}
+
+	return tags[0].Name, nil
+}
+
+// getProject returns the instance hosting the project and the full path of the
+// project including its groups.
+func (s *source) getProject(addonURL string) (sources.Instance, string, error) {
+	// the clone URL of the project ends with .git
+	trimmed := strings.TrimSuffix(sources.TrimURL(addonURL), ".git")
+
+	for _, inst := range s.instances {
+		project := strings.TrimPrefix(trimmed, inst.Prefix+"/")
+		if project != trimmed && strings.Contains(project, "/") && !strings.Contains(project, "/-/") {
+			return inst, project, nil
+		}
+	}
+
+	return sources.Instance{}, "", fmt.Errorf("the given url %s is invalid for a gitlab project", addonURL)
+}
+
+func archiveURL(inst sources.Instance, project, ref string) string {
+	u := fmt.Sprintf("%s/projects/%s/repository/archive.zip", apiURL(inst), escape(project))
+	if ref != "" {
+		u += "?sha=" + url.QueryEscape(ref)
+	}
+
+	return u
+}
+
+// escape encodes the project path to be used as project ID in the API
+func escape(project string) string {
+	return url.QueryEscape(project)
+}
+
+func (s *source) Close() error {
+	return s.downloader.Close()
+}
+
+// apiURL returns the base URL of the REST API of the instance, e.g. https://gitlab.com/api/v4
+func apiURL(inst sources.Instance) string {
+	return inst.URL + "/api/v4"
+}
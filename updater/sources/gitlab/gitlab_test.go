@@ -0,0 +1,318 @@
+package gitlab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func newGitLabSource(t *testing.T, hosts ...config.HostConfig) *source {
+	t.Helper()
+	s, err := New(nil, hosts)
+	if err != nil {
+		assert.FailNow(t, "failed to create gitlab source", err)
+	}
+
+	return s.(*source)
+}
+
+// newGitLabServer returns a test server answering the given paths with the given responses.
+// Responses ending with .zip are served from the file system, {{server}} is replaced with the server URL.
+func newGitLabServer(t *testing.T, responses map[string]string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("PRIVATE-TOKEN"))
+		path := r.URL.EscapedPath()
+		if r.URL.RawQuery != "" {
+			path += "?" + r.URL.RawQuery
+		}
+		response, ok := responses[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if filepath.Ext(response) == ".zip" {
+			content, err := os.ReadFile(response)
+			assert.NoError(t, err)
+			_, _ = w.Write(content)
+			return
+		}
+		_, _ = w.Write([]byte(strings.ReplaceAll(response, "{{server}}", server.URL)))
+	}))
+
+	return server
+}
+
+func TestNew(t *testing.T) {
+	t.Run("invalid instance url", func(t *testing.T) {
+		_, err := New(nil, []config.HostConfig{{URL: "gitlab"}})
+
+		assert.Error(t, err)
+	})
+	t.Run("gitlab.com token", func(t *testing.T) {
+		s := newGitLabSource(t, config.HostConfig{URL: "https://gitlab.com/", Token: "secret"})
+		defer s.Close()
+
+		assert.Len(t, s.instances, 1)
+	})
+}
+
+func Test_GetURLRegex(t *testing.T) {
+	s := newGitLabSource(t, config.HostConfig{URL: "https://git.example.com"})
+	defer s.Close()
+
+	tests := []struct {
+		addonURL string
+		want     bool
+	}{
+		{
+			addonURL: "https://gitlab.com/owner/addon",
+			want:     true,
+		},
+		{
+			addonURL: "gitlab.com/group/subgroup/addon/",
+			want:     true,
+		},
+		{
+			addonURL: "https://git.example.com/owner/addon",
+			want:     true,
+		},
+		{
+			addonURL: "https://gitlab.com/owner",
+			want:     false,
+		},
+		{
+			addonURL: "https://gitlab.com/owner/addon/-/releases",
+			want:     false,
+		},
+		{
+			addonURL: "https://github.com/owner/addon",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			actual := s.GetURLRegex().MatchString(tt.addonURL)
+
+			assert.Equal(t, tt.want, actual)
+		})
+	}
+}
+
+func Test_getProject(t *testing.T) {
+	s := newGitLabSource(t, config.HostConfig{URL: "https://example.com/gitlab"})
+	defer s.Close()
+
+	tests := []struct {
+		addonURL    string
+		wantAPI     string
+		wantProject string
+		wantErr     bool
+	}{
+		{
+			addonURL:    "https://gitlab.com/owner/addon/",
+			wantAPI:     "https://gitlab.com/api/v4",
+			wantProject: "owner/addon",
+		},
+		{
+			addonURL:    "https://gitlab.com/owner/addon.git",
+			wantAPI:     "https://gitlab.com/api/v4",
+			wantProject: "owner/addon",
+		},
+		{
+			addonURL:    "www.gitlab.com/group/sub/addon",
+			wantAPI:     "https://gitlab.com/api/v4",
+			wantProject: "group/sub/addon",
+		},
+		{
+			addonURL:    "https://example.com/gitlab/owner/addon",
+			wantAPI:     "https://example.com/gitlab/api/v4",
+			wantProject: "owner/addon",
+		},
+		{
+			addonURL: "https://gitlab.com/owner",
+			wantErr:  true,
+		},
+		{
+			addonURL: "https://example.com/owner/addon",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			inst, project, err := s.getProject(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantAPI, apiURL(inst))
+				assert.Equal(t, tt.wantProject, project)
+			}
+		})
+	}
+}
+
+func Test_GetAddonID(t *testing.T) {
+	s := newGitLabSource(t, config.HostConfig{URL: "https://example.com/gitlab"})
+	defer s.Close()
+
+	tests := []struct {
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{addonURL: "https://gitlab.com/Owner/Addon/", want: "gitlab.com/owner/addon"},
+		{addonURL: "http://www.gitlab.com/owner/addon", want: "gitlab.com/owner/addon"},
+		{addonURL: "https://gitlab.com/owner/addon.git", want: "gitlab.com/owner/addon"},
+		{addonURL: "https://example.com/gitlab/group/sub/addon", want: "example.com/gitlab/group/sub/addon"},
+		{addonURL: "https://gitlab.com/owner", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			got, err := s.GetAddonID(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_GetLatestVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "latest release",
+			responses: map[string]string{
+				"/api/v4/projects/owner%2Faddon/releases?per_page=1": `[{"tag_name": "v1.2.3"}]`,
+			},
+			want: "v1.2.3",
+		},
+		{
+			name: "latest tag",
+			responses: map[string]string{
+				"/api/v4/projects/owner%2Faddon/releases?per_page=1":        `[]`,
+				"/api/v4/projects/owner%2Faddon/repository/tags?per_page=1": `[{"name": "v1.0.0"}]`,
+			},
+			want: "v1.0.0",
+		},
+		{
+			name: "no releases and tags",
+			responses: map[string]string{
+				"/api/v4/projects/owner%2Faddon/releases?per_page=1":        `[]`,
+				"/api/v4/projects/owner%2Faddon/repository/tags?per_page=1": `[]`,
+			},
+			wantErr: true,
+		},
+		{
+			name:      "unknown project",
+			responses: map[string]string{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newGitLabServer(t, tt.responses)
+			defer server.Close()
+			s := newGitLabSource(t, config.HostConfig{URL: server.URL, Token: "secret"})
+			defer s.Close()
+
+			actual, err := s.GetLatestVersion(server.URL + "/owner/addon")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, actual)
+			}
+		})
+	}
+}
+
+func Test_DownloadAddon(t *testing.T) {
+	archive := filepath.Join("..", "_tests", "archive1.zip")
+
+	tests := []struct {
+		name      string
+		responses map[string]string
+		wantFile  string
+		wantErr   bool
+	}{
+		{
+			name: "release asset",
+			responses: map[string]string{
+				"/api/v4/projects/owner%2Faddon/releases?per_page=1": `[{"tag_name": "v1", "assets": {"links": [
+					{"name": "notes.txt", "url": "{{server}}/notes.txt"},
+					{"name": "addon-v1.zip", "url": "{{server}}/other.zip", "direct_asset_url": "{{server}}/asset.zip"}
+				]}}]`,
+				"/asset.zip": archive,
+			},
+			wantFile: filepath.Join("root", "a.txt"),
+		},
+		{
+			name: "release source archive",
+			responses: map[string]string{
+				"/api/v4/projects/owner%2Faddon/releases?per_page=1":           `[{"tag_name": "v1"}]`,
+				"/api/v4/projects/owner%2Faddon/repository/archive.zip?sha=v1": archive,
+			},
+			wantFile: filepath.Join("addon", "a.txt"),
+		},
+		{
+			name: "tag source archive",
+			responses: map[string]string{
+				"/api/v4/projects/owner%2Faddon/releases?per_page=1":             `[]`,
+				"/api/v4/projects/owner%2Faddon/repository/tags?per_page=1":      `[{"name": "v0.1"}]`,
+				"/api/v4/projects/owner%2Faddon/repository/archive.zip?sha=v0.1": archive,
+			},
+			wantFile: filepath.Join("addon", "a.txt"),
+		},
+		{
+			name: "failing download",
+			responses: map[string]string{
+				"/api/v4/projects/owner%2Faddon/releases?per_page=1": `[{"tag_name": "v1"}]`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newGitLabServer(t, tt.responses)
+			defer server.Close()
+			s := newGitLabSource(t, config.HostConfig{URL: server.URL, Token: "secret"})
+			defer s.Close()
+			dir := helpers.TempDir(t)
+			defer helpers.DeleteDir(t, dir)()
+
+			err := s.DownloadAddon(server.URL+"/owner/addon", dir)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.FileExists(t, filepath.Join(dir, tt.wantFile))
+			}
+		})
+	}
+}
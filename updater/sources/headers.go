@@ -0,0 +1,49 @@
+package sources
+
+import (
+	"net/http"
+)
+
+// headerTransport adds headers to all requests of the matching hosts
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]http.Header
+}
+
+// WithHeaders returns a copy of the given client which adds the given headers
+// to every request sent to the respective host, e.g. to authenticate against an API.
+func WithHeaders(client *http.Client, headers map[string]http.Header) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(headers) == 0 {
+		return client
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	c := *client
+	c.Transport = &headerTransport{
+		base:    base,
+		headers: headers,
+	}
+
+	return &c
+}
+
+func (h *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers, ok := h.headers[req.URL.Host]
+	if !ok {
+		return h.base.RoundTrip(req)
+	}
+
+	r := req.Clone(req.Context())
+	for key, values := range headers {
+		r.Header[key] = values
+	}
+
+	return h.base.RoundTrip(r)
+}
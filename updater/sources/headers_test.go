@@ -0,0 +1,45 @@
+package sources
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHeaders(t *testing.T) {
+	t.Run("no headers", func(t *testing.T) {
+		client := new(http.Client)
+
+		got := WithHeaders(client, nil)
+
+		assert.Same(t, client, got)
+	})
+	t.Run("add headers to matching host", func(t *testing.T) {
+		received := make([]string, 0)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+			received = append(received, r.Header.Get("PRIVATE-TOKEN"))
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			assert.FailNow(t, "failed to parse server url", err)
+		}
+		other := httptest.NewServer(mux)
+		defer other.Close()
+
+		client := WithHeaders(nil, map[string]http.Header{
+			u.Host: {"Private-Token": []string{"secret"}},
+		})
+		_, err = client.Get(server.URL)
+		assert.NoError(t, err)
+		_, err = client.Get(other.URL)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"secret", ""}, received)
+	})
+}
@@ -0,0 +1,27 @@
+package sources
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLID returns the canonical ID of an addon identified by its web URL. That is the lower case
+// host without www. followed by the path without trailing slash and the query of the URL,
+// e.g. example.com/addon.zip. The scheme and the fragment are dropped.
+func URLID(addonURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(addonURL))
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("the url %s has no host", addonURL)
+	}
+
+	id := strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		id += "?" + u.RawQuery
+	}
+
+	return id, nil
+}
@@ -0,0 +1,34 @@
+package sources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLID(t *testing.T) {
+	tests := []struct {
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{addonURL: "https://www.Example.com/Addon.zip", want: "example.com/Addon.zip"},
+		{addonURL: "http://example.com/addons/page/", want: "example.com/addons/page"},
+		{addonURL: "https://example.com/download?id=1#files", want: "example.com/download?id=1"},
+		{addonURL: "example.com/addon.zip", wantErr: true},
+		{addonURL: "https://example.com/%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			got, err := URLID(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
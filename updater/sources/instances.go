@@ -0,0 +1,84 @@
+package sources
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/config"
+)
+
+var schemeRegex = regexp.MustCompile(`^https?://`)
+
+// Instance is a server of a self-hostable code hosting platform, e.g. GitLab or Gitea
+type Instance struct {
+	// Prefix is the host and optional path of the instance without scheme, e.g. gitlab.com
+	Prefix string
+	// URL is the base URL of the instance, e.g. https://gitlab.com
+	URL string
+}
+
+// Instances are the servers a source resolves repositories from
+type Instances []Instance
+
+// NewInstances returns the instance of the default URL followed by the given hosts. Configuring
+// the default instance again only adds its token. The returned copy of the client sends the
+// header created by the given function for the token of a host along with every request to it.
+func NewInstances(client *http.Client, defaultURL string, hosts []config.HostConfig, tokenHeader func(token string) http.Header) (Instances, *http.Client, error) {
+	hosts = append([]config.HostConfig{{URL: defaultURL}}, hosts...)
+
+	instances := make(Instances, 0, len(hosts))
+	headers := make(map[string]http.Header)
+	for _, host := range hosts {
+		u, err := url.Parse(strings.TrimSuffix(host.URL, "/"))
+		if err != nil || u.Host == "" {
+			return nil, nil, fmt.Errorf("invalid instance url: %s", host.URL)
+		}
+
+		prefix := u.Host + u.Path
+		if host.Token != "" {
+			headers[u.Host] = tokenHeader(host.Token)
+		}
+		if instances.has(prefix) {
+			continue
+		}
+
+		instances = append(instances, Instance{
+			Prefix: prefix,
+			URL:    u.String(),
+		})
+	}
+
+	return instances, WithHeaders(client, headers), nil
+}
+
+// Pattern returns the regular expression matching the prefix of any of the instances
+// with an optional scheme and www subdomain
+func (i Instances) Pattern() string {
+	prefixes := make([]string, len(i))
+	for j, inst := range i {
+		prefixes[j] = regexp.QuoteMeta(inst.Prefix)
+	}
+
+	return `^(https?://)?(www\.)?(` + strings.Join(prefixes, "|") + `)`
+}
+
+// TrimURL returns the URL without its scheme, www subdomain and trailing slash
+// to be compared with the prefixes of the instances
+func TrimURL(addonURL string) string {
+	trimmed := schemeRegex.ReplaceAllString(strings.TrimSuffix(addonURL, "/"), "")
+
+	return strings.TrimPrefix(trimmed, "www.")
+}
+
+func (i Instances) has(prefix string) bool {
+	for _, inst := range i {
+		if inst.Prefix == prefix {
+			return true
+		}
+	}
+
+	return false
+}
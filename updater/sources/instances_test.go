@@ -0,0 +1,63 @@
+package sources
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/config"
+)
+
+func TestNewInstances(t *testing.T) {
+	tokenHeader := func(token string) http.Header {
+		return http.Header{"Private-Token": []string{token}}
+	}
+
+	t.Run("invalid instance url", func(t *testing.T) {
+		_, _, err := NewInstances(nil, "https://example.com", []config.HostConfig{{URL: "example"}}, tokenHeader)
+
+		assert.Error(t, err)
+	})
+	t.Run("default and self-hosted instances", func(t *testing.T) {
+		client := new(http.Client)
+
+		instances, got, err := NewInstances(client, "https://example.com", []config.HostConfig{
+			{URL: "https://example.com/", Token: "secret"},
+			{URL: "https://git.example.org/gitlab/"},
+		}, tokenHeader)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Instances{
+			{Prefix: "example.com", URL: "https://example.com"},
+			{Prefix: "git.example.org/gitlab", URL: "https://git.example.org/gitlab"},
+		}, instances)
+		assert.NotSame(t, client, got)
+		assert.Equal(t, `^(https?://)?(www\.)?(example\.com|git\.example\.org/gitlab)`, instances.Pattern())
+	})
+	t.Run("no tokens", func(t *testing.T) {
+		client := new(http.Client)
+
+		_, got, err := NewInstances(client, "https://example.com", nil, tokenHeader)
+
+		assert.NoError(t, err)
+		assert.Same(t, client, got)
+	})
+}
+
+func TestTrimURL(t *testing.T) {
+	tests := []struct {
+		addonURL string
+		want     string
+	}{
+		{"https://www.example.com/owner/repo/", "example.com/owner/repo"},
+		{"http://example.com/owner/repo", "example.com/owner/repo"},
+		{"example.com/owner/repo", "example.com/owner/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			assert.Equal(t, tt.want, TrimURL(tt.addonURL))
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package local
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/toc"
+	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/util"
+)
+
+var (
+	regex       = regexp.MustCompile(`^file://.+`)
+	volumeRegex = regexp.MustCompile(`^/[a-zA-Z]:`)
+)
+
+// source is the source for addons on the local file system, either as
+// zip archive or as directory
+type source struct {
+	tempDir string
+	// archives already extracted to read their TOC files, by their path
+	extracted map[string]string
+}
+
+// New returns a new update source for file:// URLs pointing to a local zip archive
+// or directory. A directory is either an addon folder itself or contains addon folders.
+func New() (updater.UpdateSource, error) {
+	dir, err := os.MkdirTemp("", "wow-updater")
+	if err != nil {
+		return nil, err
+	}
+
+	return &source{
+		tempDir:   dir,
+		extracted: make(map[string]string),
+	}, nil
+}
+
+func (source) GetURLRegex() *regexp.Regexp {
+	return regex
+}
+
+// GetAddonID returns the cleaned path of the archive or directory as file:// URL,
+// e.g. file:///path/to/addon.zip
+func (source) GetAddonID(addonURL string) (string, error) {
+	path, err := cleanPath(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// file:///C:/path on windows
+		path = "/" + path
+	}
+
+	return "file://" + path, nil
+}
+
+// GetLatestVersion returns the version field of the addon's TOC file.
+// If there is none the hash of the archive or directory content is used.
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	path, err := getPath(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := s.getDirectory(path)
+	if err != nil {
+		return "", err
+	}
+
+	folders, err := addonFolders(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, folder := range folders {
+		version, err := readTOCVersion(folder)
+		if err != nil {
+			return "", err
+		}
+		if version != "" {
+			return version, nil
+		}
+	}
+
+	var hash string
+	if dir == path {
+		hash, err = util.HashDir(path)
+	} else {
+		hash, err = util.HashFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hash, nil
+}
+
+// DownloadAddon unzips the archive or copies the addon folders of the directory
+// to the given directory
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	path, err := getPath(addonURL)
+	if err != nil {
+		return err
+	}
+
+	if !isDir(path) {
+		_, err = util.Unzip(path, dir)
+		return err
+	}
+
+	folders, err := addonFolders(path)
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range folders {
+		dst := filepath.Join(dir, filepath.Base(folder))
+		same, err := overlaps(folder, dst)
+		if err != nil {
+			return err
+		}
+		if same {
+			// the addon folder is already installed in place
+			continue
+		}
+
+		err = util.CopyDir(folder, dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// overlaps returns true if both paths point to the same directory. Returns an error if one of
+// them contains the other, as replacing the destination would delete the source.
+func overlaps(src, dst string) (bool, error) {
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return false, err
+	}
+	dst, err = filepath.Abs(dst)
+	if err != nil {
+		return false, err
+	}
+
+	if src == dst {
+		return true, nil
+	}
+	if contains(src, dst) || contains(dst, src) {
+		return false, fmt.Errorf("cannot copy %s to %s as one contains the other", src, dst)
+	}
+
+	return false, nil
+}
+
+// contains returns true if the path is within the parent directory
+func contains(parent, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// getDirectory returns the given path for a directory or the path of the
+// extracted content of a zip archive.
+func (s *source) getDirectory(path string) (string, error) {
+	if isDir(path) {
+		return path, nil
+	}
+
+	if dir, ok := s.extracted[path]; ok {
+		return dir, nil
+	}
+
+	dir, err := os.MkdirTemp(s.tempDir, "*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = util.Unzip(path, dir)
+	if err != nil {
+		return "", err
+	}
+	s.extracted[path] = dir
+
+	return dir, nil
+}
+
+// getPath returns the local file path of the given file:// URL. Returns an error if it does not exist.
+func getPath(addonURL string) (string, error) {
+	path, err := cleanPath(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// cleanPath returns the cleaned local file path of the given file:// URL
+func cleanPath(addonURL string) (string, error) {
+	if !regex.MatchString(addonURL) {
+		return "", fmt.Errorf("the given url %s is not a file:// url", addonURL)
+	}
+
+	path, err := url.PathUnescape(strings.TrimPrefix(addonURL, "file://"))
+	if err != nil {
+		return "", err
+	}
+	path = strings.TrimPrefix(path, "localhost/")
+	// file:///C:/path on windows
+	if volumeRegex.MatchString(path) {
+		path = path[1:]
+	}
+
+	return filepath.Clean(filepath.FromSlash(path)), nil
+}
+
+// addonFolders returns the given directory if it contains a TOC file.
+// Otherwise all sub directories with a TOC file are returned.
+func addonFolders(dir string) ([]string, error) {
+	if hasTOC(dir) {
+		return []string{dir}, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]string, 0)
+	for _, entry := range entries {
+		folder := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && hasTOC(folder) {
+			folders = append(folders, folder)
+		}
+	}
+
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("no addon folder with a .toc file found in %s", dir)
+	}
+
+	return folders, nil
+}
+
+func tocFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.toc"))
+	sort.Strings(files)
+	return files
+}
+
+func hasTOC(dir string) bool {
+	return len(tocFiles(dir)) > 0
+}
+
+// readTOCVersion returns the ## Version field of the TOC files in the directory
+// or an empty string if there is none.
+func readTOCVersion(dir string) (string, error) {
+	for _, file := range tocFiles(dir) {
+		f, err := toc.ParseFile(file)
+		if err != nil {
+			return "", err
+		}
+		if f.Version != "" {
+			return f.Version, nil
+		}
+	}
+
+	return "", nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func (s *source) Close() error {
+	return os.RemoveAll(s.tempDir)
+}
@@ -0,0 +1,266 @@
+package local
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func newLocalSource(t *testing.T) *source {
+	t.Helper()
+	s, err := New()
+	if err != nil {
+		assert.FailNow(t, "failed to create local source", err)
+	}
+
+	return s.(*source)
+}
+
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "file://" + path
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			assert.FailNow(t, "failed to create directory", err)
+		}
+		if err := os.WriteFile(path, []byte(content), os.FileMode(0666)); err != nil {
+			assert.FailNow(t, "failed to write file", err)
+		}
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		assert.FailNow(t, "failed to create zip file", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+}
+
+func Test_GetURLRegex(t *testing.T) {
+	source := newLocalSource(t)
+	defer source.Close()
+
+	assert.True(t, source.GetURLRegex().MatchString("file:///home/user/addons/MyAddon"))
+	assert.True(t, source.GetURLRegex().MatchString("file:///C:/addons/MyAddon.zip"))
+	assert.False(t, source.GetURLRegex().MatchString("https://example.com/MyAddon.zip"))
+	assert.False(t, source.GetURLRegex().MatchString("file://"))
+}
+
+func Test_getPath(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	writeFiles(t, dir, map[string]string{"My Addon/a.toc": ""})
+
+	t.Run("escaped path", func(t *testing.T) {
+		got, err := getPath(strings.ReplaceAll(fileURL(filepath.Join(dir, "My Addon")), " ", "%20"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "My Addon"), got)
+	})
+	t.Run("not existing path", func(t *testing.T) {
+		_, err := getPath(fileURL(filepath.Join(dir, "missing")))
+
+		assert.Error(t, err)
+	})
+	t.Run("no file url", func(t *testing.T) {
+		_, err := getPath(dir)
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_GetAddonID(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+
+	t.Run("cleaned path", func(t *testing.T) {
+		got, err := source{}.GetAddonID(fileURL(filepath.Join(dir, "My Addon")) + "/sub/..")
+
+		assert.NoError(t, err)
+		assert.Equal(t, fileURL(filepath.Join(dir, "My Addon")), got)
+	})
+	t.Run("no file url", func(t *testing.T) {
+		_, err := source{}.GetAddonID(dir)
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_GetLatestVersion(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	writeFiles(t, dir, map[string]string{
+		"versioned/MyAddon.toc":        "## Interface: 90100\n## Version: 1.2.3\n",
+		"unversioned/MyAddon/Core.toc": "## Title: Core\n",
+		"unversioned/MyAddon/Core.lua": "print('hello')",
+		"empty/readme.txt":             "",
+	})
+	writeZip(t, filepath.Join(dir, "versioned.zip"), map[string]string{
+		"MyAddon/MyAddon.toc":     "## Interface: 1.13\n## version: 2.0\n",
+		"MyAddon_Options/opt.toc": "",
+	})
+	writeZip(t, filepath.Join(dir, "unversioned.zip"), map[string]string{
+		"MyAddon/MyAddon.toc": "## Title: MyAddon\n",
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "directory with toc version",
+			path: "versioned",
+			want: "1.2.3",
+		},
+		{
+			name: "directory hash",
+			path: "unversioned",
+			want: "sha256:",
+		},
+		{
+			name:    "directory without addon",
+			path:    "empty",
+			wantErr: true,
+		},
+		{
+			name: "zip with toc version",
+			path: "versioned.zip",
+			want: "2.0",
+		},
+		{
+			name: "zip hash",
+			path: "unversioned.zip",
+			want: "sha256:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newLocalSource(t)
+			defer source.Close()
+
+			got, err := source.GetLatestVersion(fileURL(filepath.Join(dir, tt.path)))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(got, tt.want), got)
+			}
+		})
+	}
+}
+
+func Test_DownloadAddon(t *testing.T) {
+	src := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, src)()
+	writeFiles(t, src, map[string]string{
+		"Suite/AddonA/AddonA.toc":   "",
+		"Suite/AddonB/AddonB.toc":   "",
+		"Suite/AddonB/Libs/lib.lua": "",
+		"Suite/notes.txt":           "",
+		"Single/Single.toc":         "",
+	})
+	writeZip(t, filepath.Join(src, "archive.zip"), map[string]string{
+		"Zipped/Zipped.toc": "",
+	})
+
+	tests := []struct {
+		name      string
+		path      string
+		wantFiles []string
+	}{
+		{
+			name:      "directory of addon folders",
+			path:      "Suite",
+			wantFiles: []string{"AddonA/AddonA.toc", "AddonB/Libs/lib.lua"},
+		},
+		{
+			name:      "addon folder",
+			path:      "Single",
+			wantFiles: []string{"Single/Single.toc"},
+		},
+		{
+			name:      "zip archive",
+			path:      "archive.zip",
+			wantFiles: []string{"Zipped/Zipped.toc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newLocalSource(t)
+			defer source.Close()
+			dir := helpers.TempDir(t)
+			defer helpers.DeleteDir(t, dir)()
+
+			err := source.DownloadAddon(fileURL(filepath.Join(src, tt.path)), dir)
+
+			assert.NoError(t, err)
+			for _, file := range tt.wantFiles {
+				assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(file)))
+			}
+			assert.NoFileExists(t, filepath.Join(dir, "notes.txt"))
+		})
+	}
+}
+
+func Test_DownloadAddon_InPlace(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	writeFiles(t, dir, map[string]string{
+		"InHouse/InHouse.toc":      "",
+		"Nested/Nested/Nested.toc": "",
+		"Other/Other.toc":          "",
+		"Other/Libs/InHouse.lua":   "",
+	})
+	source := newLocalSource(t)
+	defer source.Close()
+
+	t.Run("addon folder", func(t *testing.T) {
+		err := source.DownloadAddon(fileURL(filepath.Join(dir, "InHouse")), dir)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "InHouse", "InHouse.toc"))
+	})
+	t.Run("addons directory", func(t *testing.T) {
+		err := source.DownloadAddon(fileURL(dir), dir)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "InHouse", "InHouse.toc"))
+		assert.FileExists(t, filepath.Join(dir, "Other", "Libs", "InHouse.lua"))
+	})
+	t.Run("folder within the destination", func(t *testing.T) {
+		err := source.DownloadAddon(fileURL(filepath.Join(dir, "Nested", "Nested")), dir)
+
+		assert.Error(t, err)
+		assert.FileExists(t, filepath.Join(dir, "Nested", "Nested", "Nested.toc"))
+	})
+}
@@ -0,0 +1,402 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/unly/wow-addon-updater/util"
+)
+
+// ErrUnsupportedExternal is returned by an ExternalFetcher for an external it can not handle.
+var ErrUnsupportedExternal = errors.New("unsupported external")
+
+var pkgMetaFiles = []string{".pkgmeta", "pkgmeta.yaml", "pkgmeta.yml"}
+
+// ExternalFetcher downloads the external of the given URL at the optional tag
+// and extracts its content to the dest directory.
+type ExternalFetcher func(url, tag, dest string) error
+
+// Externals is embedded by the sources installing source archives. It holds the fetcher set
+// by the registry for the externals hosted elsewhere than the repository of the addon.
+type Externals struct {
+	fetch ExternalFetcher
+}
+
+// SetExternalFetcher sets the fetcher for the externals of the addons of the source
+func (e *Externals) SetExternalFetcher(fetch ExternalFetcher) {
+	e.fetch = fetch
+}
+
+// Fetcher returns the fetcher set by the registry or the given one of the source itself
+func (e *Externals) Fetcher(own ExternalFetcher) ExternalFetcher {
+	if e.fetch != nil {
+		return e.fetch
+	}
+
+	return own
+}
+
+// MissingExternalsError is returned by InstallSourceArchive if the addon was installed
+// without the externals no fetcher supports, e.g. subversion repositories.
+type MissingExternalsError struct {
+	URLs []string
+}
+
+func (e *MissingExternalsError) Error() string {
+	return fmt.Sprintf("installed without the unsupported externals %s", strings.Join(e.URLs, ", "))
+}
+
+// PkgMeta contains the packaging instructions of an addon repository as used by the
+// BigWigs and CurseForge packagers.
+type PkgMeta struct {
+	// folder name of the packaged addon
+	PackageAs string `yaml:"package-as"`
+	// externals to check out, keyed by the path within the addon
+	Externals map[string]External `yaml:"externals"`
+	// folders to move to the top level, keyed by the path within the package
+	MoveFolders map[string]string `yaml:"move-folders"`
+	// paths or patterns to leave out of the package
+	Ignore []string `yaml:"ignore"`
+}
+
+// External is a library or dependency to include in the package.
+type External struct {
+	URL string `yaml:"url"`
+	Tag string `yaml:"tag"`
+}
+
+// UnmarshalYAML supports both the short form of an external as plain URL
+// and the long form with url and tag fields.
+func (e *External) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.URL = value.Value
+		return nil
+	}
+
+	type external External
+	return value.Decode((*external)(e))
+}
+
+// ReadPkgMeta reads in the packaging instructions of the given directory.
+// Returns nil if the directory does not contain a .pkgmeta file.
+func ReadPkgMeta(dir string) (*PkgMeta, error) {
+	for _, name := range pkgMetaFiles {
+		file := filepath.Join(dir, name)
+		if !util.FileExists(file) {
+			continue
+		}
+
+		in, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var meta PkgMeta
+		err = yaml.Unmarshal(in, &meta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+
+		err = meta.validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", name, err)
+		}
+
+		return &meta, nil
+	}
+
+	return nil, nil
+}
+
+// InstallSourceArchive extracts a source archive with a single root directory to the given
+// directory and installs it as addon folder with the given name. The packaging instructions
+// of a .pkgmeta file within the archive are applied, externals are retrieved via the fetcher.
+// Existing addon folders are replaced entirely. Returns a MissingExternalsError after the
+// installation if the fetcher does not support some of the externals.
+func InstallSourceArchive(zipPath, dir, name string, fetch ExternalFetcher) error {
+	staging, err := os.MkdirTemp(dir, ".wow-updater-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	root, err := extractRoot(zipPath, staging)
+	if err != nil {
+		return err
+	}
+
+	meta, err := ReadPkgMeta(root)
+	if err != nil {
+		return err
+	}
+
+	folders := map[string]string{}
+	var unsupported []string
+	if meta != nil {
+		if meta.PackageAs != "" {
+			name = meta.PackageAs
+		}
+
+		folders, unsupported, err = meta.apply(root, staging, fetch)
+		if err != nil {
+			return err
+		}
+	}
+	err = removeHiddenFiles(root)
+	if err != nil {
+		return err
+	}
+	// a folder moved to the package name replaces the rest of the package
+	if _, ok := folders[name]; !ok {
+		folders[name] = root
+	}
+
+	for folder, src := range folders {
+		err = replaceDir(src, filepath.Join(dir, folder))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(unsupported) > 0 {
+		return &MissingExternalsError{URLs: unsupported}
+	}
+
+	return nil
+}
+
+// apply applies the ignore rules, externals and folder moves to the package in root.
+// Returns the moved folders by their name pointing to their location in the staging directory
+// and the sorted URLs of the externals the fetcher does not support.
+func (p *PkgMeta) apply(root, staging string, fetch ExternalFetcher) (map[string]string, []string, error) {
+	err := p.removeIgnored(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unsupported := make([]string, 0)
+	for dest, external := range p.Externals {
+		err = fetchExternal(fetch, external, filepath.Join(root, filepath.FromSlash(dest)))
+		if errors.Is(err, ErrUnsupportedExternal) {
+			unsupported = append(unsupported, external.URL)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	sort.Strings(unsupported)
+
+	folders := make(map[string]string, len(p.MoveFolders))
+	for src, folder := range p.MoveFolders {
+		// the source paths are prefixed with the package name
+		parts := strings.SplitN(path.Clean(src), "/", 2)
+		moved := filepath.Join(staging, folder)
+		err = os.Rename(filepath.Join(root, filepath.FromSlash(parts[1])), moved)
+		if err != nil {
+			return nil, nil, err
+		}
+		folders[folder] = moved
+	}
+
+	return folders, unsupported, nil
+}
+
+// validate returns an error if one of the paths is absolute or leaves the package, as the
+// packaged folders replace the ones of the same name in the AddOns directory.
+func (p *PkgMeta) validate() error {
+	if p.PackageAs != "" && !isFolderName(p.PackageAs) {
+		return fmt.Errorf("package-as %s is not a folder name", p.PackageAs)
+	}
+
+	for dest := range p.Externals {
+		if !isWithin(dest) {
+			return fmt.Errorf("externals entry %s is outside of the package", dest)
+		}
+	}
+
+	for src, folder := range p.MoveFolders {
+		// the source paths are prefixed with the package name
+		parts := strings.SplitN(path.Clean(src), "/", 2)
+		if !isWithin(src) || len(parts) != 2 || !isWithin(parts[1]) {
+			return fmt.Errorf("move-folders entry %s is outside of the package", src)
+		}
+		if !isFolderName(folder) {
+			return fmt.Errorf("move-folders entry %s: %s is not a folder name", src, folder)
+		}
+	}
+
+	return nil
+}
+
+// isWithin returns true if the relative slash separated path resolves to a path
+// below the directory it is relative to
+func isWithin(rel string) bool {
+	if rel == "" || path.IsAbs(rel) || filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return false
+	}
+
+	root := string(filepath.Separator) + "root"
+	rel, err := filepath.Rel(root, filepath.Join(root, filepath.FromSlash(rel)))
+
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isFolderName returns true if the name is a single folder below the directory it is relative to
+func isFolderName(name string) bool {
+	return isWithin(name) && !strings.ContainsAny(filepath.Clean(filepath.FromSlash(name)), `/\`)
+}
+
+func (p *PkgMeta) removeIgnored(root string) error {
+	if len(p.Ignore) == 0 {
+		return nil
+	}
+
+	return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || file == root {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+
+		if !p.isIgnored(filepath.ToSlash(rel)) {
+			return nil
+		}
+
+		err = os.RemoveAll(file)
+		if err == nil && d.IsDir() {
+			return filepath.SkipDir
+		}
+		return err
+	})
+}
+
+// isIgnored returns true if an ignore pattern matches the relative path or one of its
+// parent directories. The patterns are matched per path segment, e.g. Libs/*/Docs matches
+// Libs/LibFoo/Docs as well as any file within it.
+func (p *PkgMeta) isIgnored(rel string) bool {
+	segments := strings.Split(rel, "/")
+	for _, pattern := range p.Ignore {
+		if matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), segments) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSegments returns true if the segments of the pattern match the leading segments of the path
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) > len(segments) {
+		return false
+	}
+
+	for i, part := range pattern {
+		if ok, err := path.Match(part, segments[i]); err != nil || !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fetchExternal retrieves the external via the fetcher. Returns an error wrapping
+// ErrUnsupportedExternal if there is no fetcher or it does not support the external.
+func fetchExternal(fetch ExternalFetcher, external External, dest string) error {
+	if fetch == nil {
+		return fmt.Errorf("failed to fetch external %s: %w", external.URL, ErrUnsupportedExternal)
+	}
+
+	err := fetch(external.URL, external.Tag, dest)
+	if err != nil {
+		return fmt.Errorf("failed to fetch external %s: %w", external.URL, err)
+	}
+
+	return nil
+}
+
+// ExtractExternal extracts a source archive with a single root directory and
+// moves the content of the root to the dest directory.
+func ExtractExternal(zipPath, dest string) error {
+	err := os.MkdirAll(filepath.Dir(dest), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dest), ".wow-updater-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	root, err := extractRoot(zipPath, staging)
+	if err != nil {
+		return err
+	}
+
+	err = removeHiddenFiles(root)
+	if err != nil {
+		return err
+	}
+
+	return replaceDir(root, dest)
+}
+
+// extractRoot unzips the archive to the staging directory and returns the path
+// of the single root directory of the archive.
+func extractRoot(zipPath, staging string) (string, error) {
+	_, err := util.Unzip(zipPath, staging)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", errors.New("the source archive does not have a single root directory")
+	}
+
+	return filepath.Join(staging, entries[0].Name()), nil
+}
+
+// removeHiddenFiles removes the top level files and folders starting with a dot,
+// such as .git, .github or .pkgmeta, which the packagers leave out as well.
+func removeHiddenFiles(root string) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			err = os.RemoveAll(filepath.Join(root, entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func replaceDir(src, dst string) error {
+	err := os.RemoveAll(dst)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(src, dst)
+}
@@ -0,0 +1,369 @@
+package sources
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+func writeZip(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	f, err := os.CreateTemp(dir, "*.zip")
+	if err != nil {
+		assert.FailNow(t, "failed to create zip file", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			assert.FailNow(t, "failed to add file to zip", err)
+		}
+		_, err = fw.Write([]byte(content))
+		if err != nil {
+			assert.FailNow(t, "failed to write file to zip", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		assert.FailNow(t, "failed to write zip", err)
+	}
+
+	return f.Name()
+}
+
+func TestReadPkgMeta(t *testing.T) {
+	t.Run("no pkgmeta", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+
+		meta, err := ReadPkgMeta(dir)
+
+		assert.NoError(t, err)
+		assert.Nil(t, meta)
+	})
+	t.Run("invalid pkgmeta", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		err := os.WriteFile(filepath.Join(dir, ".pkgmeta"), []byte("ignore: {"), os.FileMode(0666))
+		if err != nil {
+			assert.FailNow(t, "failed to write .pkgmeta", err)
+		}
+
+		_, err = ReadPkgMeta(dir)
+
+		assert.Error(t, err)
+	})
+	t.Run("short and long externals", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		content := `
+package-as: Addon
+externals:
+  Libs/LibStub: https://repos.wowace.com/wow/libstub/trunk
+  Libs/LibFoo:
+    url: https://github.com/owner/libfoo
+    tag: v1.0
+move-folders:
+  Addon/Modules/Foo: Addon_Foo
+ignore:
+  - README.md
+`
+		err := os.WriteFile(filepath.Join(dir, "pkgmeta.yaml"), []byte(content), os.FileMode(0666))
+		if err != nil {
+			assert.FailNow(t, "failed to write pkgmeta.yaml", err)
+		}
+
+		meta, err := ReadPkgMeta(dir)
+
+		assert.NoError(t, err)
+		want := &PkgMeta{
+			PackageAs: "Addon",
+			Externals: map[string]External{
+				"Libs/LibStub": {URL: "https://repos.wowace.com/wow/libstub/trunk"},
+				"Libs/LibFoo":  {URL: "https://github.com/owner/libfoo", Tag: "v1.0"},
+			},
+			MoveFolders: map[string]string{
+				"Addon/Modules/Foo": "Addon_Foo",
+			},
+			Ignore: []string{"README.md"},
+		}
+		assert.Equal(t, want, meta)
+	})
+	t.Run("paths outside of the package", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			content string
+		}{
+			{name: "package-as parent", content: "package-as: ..\n"},
+			{name: "package-as current", content: "package-as: .\n"},
+			{name: "package-as nested", content: "package-as: Addon/../../Interface\n"},
+			{name: "package-as absolute", content: "package-as: /tmp/Addon\n"},
+			{name: "externals parent", content: "externals:\n  ../Libs/LibStub: https://github.com/owner/libstub\n"},
+			{name: "externals root", content: "externals:\n  Libs/..: https://github.com/owner/libstub\n"},
+			{name: "externals absolute", content: "externals:\n  /Libs/LibStub: https://github.com/owner/libstub\n"},
+			{name: "move-folders source parent", content: "move-folders:\n  Addon/../../Foo: Addon_Foo\n"},
+			{name: "move-folders source root", content: "move-folders:\n  Addon: Addon_Foo\n"},
+			{name: "move-folders source absolute", content: "move-folders:\n  /Addon/Foo: Addon_Foo\n"},
+			{name: "move-folders folder parent", content: "move-folders:\n  Addon/Foo: ..\n"},
+			{name: "move-folders folder current", content: "move-folders:\n  Addon/Foo: .\n"},
+			{name: "move-folders folder nested", content: "move-folders:\n  Addon/Foo: Addon/Foo\n"},
+			{name: "move-folders folder absolute", content: "move-folders:\n  Addon/Foo: /Addon_Foo\n"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				dir := helpers.TempDir(t)
+				defer helpers.DeleteDir(t, dir)()
+				err := os.WriteFile(filepath.Join(dir, ".pkgmeta"), []byte(tt.content), os.FileMode(0666))
+				if err != nil {
+					assert.FailNow(t, "failed to write .pkgmeta", err)
+				}
+
+				_, err = ReadPkgMeta(dir)
+
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestInstallSourceArchive(t *testing.T) {
+	t.Run("no single root directory", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		zipPath := writeZip(t, "", map[string]string{"a.txt": "", "b/c.txt": ""})
+		defer helpers.DeleteFile(t, zipPath)()
+
+		err := InstallSourceArchive(zipPath, dir, "addon", nil)
+
+		assert.Error(t, err)
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries)
+	})
+	t.Run("replace existing folder", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		err := os.MkdirAll(filepath.Join(dir, "addon"), os.ModePerm)
+		if err != nil {
+			assert.FailNow(t, "failed to create addon dir", err)
+		}
+		err = os.WriteFile(filepath.Join(dir, "addon", "stale.lua"), []byte{}, os.FileMode(0666))
+		if err != nil {
+			assert.FailNow(t, "failed to write stale file", err)
+		}
+		zipPath := writeZip(t, "", map[string]string{
+			"owner-addon-123/addon.toc":  "",
+			"owner-addon-123/.gitignore": "",
+		})
+		defer helpers.DeleteFile(t, zipPath)()
+
+		err = InstallSourceArchive(zipPath, dir, "addon", nil)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "addon", "addon.toc"))
+		assert.NoFileExists(t, filepath.Join(dir, "addon", "stale.lua"))
+		assert.NoFileExists(t, filepath.Join(dir, "addon", ".gitignore"))
+		entries, _ := os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("apply pkgmeta", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		zipPath := writeZip(t, "", map[string]string{
+			"owner-repo-123/.pkgmeta": `
+package-as: Addon
+externals:
+  Libs/LibFoo:
+    url: https://github.com/owner/libfoo
+    tag: v1.0
+move-folders:
+  Addon/Modules/Foo: Addon_Foo
+ignore:
+  - README.md
+  - Docs
+  - "*.psd"
+  - Modules/*/Tests
+`,
+			"owner-repo-123/Addon.toc":             "",
+			"owner-repo-123/README.md":             "",
+			"owner-repo-123/Docs/index.md":         "",
+			"owner-repo-123/Media/icon.psd":        "",
+			"owner-repo-123/logo.psd":              "",
+			"owner-repo-123/Modules/Foo/Foo.toc":   "",
+			"owner-repo-123/Modules/Bar/Bar.lua":   "",
+			"owner-repo-123/Modules/Bar/Tests/a":   "",
+			"owner-repo-123/.github/workflows/a.y": "",
+		})
+		defer helpers.DeleteFile(t, zipPath)()
+		fetched := make([]string, 0)
+		fetch := func(url, tag, dest string) error {
+			if url != "https://github.com/owner/libfoo" {
+				return ErrUnsupportedExternal
+			}
+			fetched = append(fetched, url+"@"+tag)
+			if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(dest, "LibFoo.lua"), []byte{}, os.FileMode(0666))
+		}
+
+		err := InstallSourceArchive(zipPath, dir, "repo", fetch)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://github.com/owner/libfoo@v1.0"}, fetched)
+		assert.NoDirExists(t, filepath.Join(dir, "repo"))
+		assert.FileExists(t, filepath.Join(dir, "Addon", "Addon.toc"))
+		assert.FileExists(t, filepath.Join(dir, "Addon", "Libs", "LibFoo", "LibFoo.lua"))
+		assert.FileExists(t, filepath.Join(dir, "Addon", "Modules", "Bar", "Bar.lua"))
+		assert.NoDirExists(t, filepath.Join(dir, "Addon", "Modules", "Bar", "Tests"))
+		assert.FileExists(t, filepath.Join(dir, "Addon", "Media", "icon.psd"))
+		assert.NoFileExists(t, filepath.Join(dir, "Addon", "README.md"))
+		assert.NoFileExists(t, filepath.Join(dir, "Addon", "logo.psd"))
+		assert.NoFileExists(t, filepath.Join(dir, "Addon", ".pkgmeta"))
+		assert.NoDirExists(t, filepath.Join(dir, "Addon", "Docs"))
+		assert.NoDirExists(t, filepath.Join(dir, "Addon", ".github"))
+		assert.NoDirExists(t, filepath.Join(dir, "Addon", "Modules", "Foo"))
+		assert.FileExists(t, filepath.Join(dir, "Addon_Foo", "Foo.toc"))
+	})
+	t.Run("move folder to package name", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		zipPath := writeZip(t, "", map[string]string{
+			"root/.pkgmeta":            "package-as: Addon\nmove-folders:\n  Addon/Addon: Addon\n  Addon/Addon_Options: Addon_Options\n",
+			"root/README.md":           "",
+			"root/Addon/a.toc":         "",
+			"root/Addon_Options/b.lua": "",
+		})
+		defer helpers.DeleteFile(t, zipPath)()
+
+		err := InstallSourceArchive(zipPath, dir, "repo", nil)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "Addon", "a.toc"))
+		assert.NoFileExists(t, filepath.Join(dir, "Addon", "README.md"))
+		assert.FileExists(t, filepath.Join(dir, "Addon_Options", "b.lua"))
+	})
+	t.Run("package as parent directory", func(t *testing.T) {
+		parent := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, parent)()
+		dir := filepath.Join(parent, "AddOns")
+		err := os.MkdirAll(filepath.Join(dir, "Other"), os.ModePerm)
+		if err != nil {
+			assert.FailNow(t, "failed to create addon dir", err)
+		}
+		zipPath := writeZip(t, "", map[string]string{
+			"root/.pkgmeta": "package-as: ..\n",
+			"root/a.toc":    "",
+		})
+		defer helpers.DeleteFile(t, zipPath)()
+
+		err = InstallSourceArchive(zipPath, dir, "addon", nil)
+
+		assert.Error(t, err)
+		assert.DirExists(t, filepath.Join(dir, "Other"))
+	})
+	t.Run("unsupported external", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		zipPath := writeZip(t, "", map[string]string{
+			"root/.pkgmeta": "externals:\n  Libs/LibStub: svn://repos.wowace.com/wow/libstub/trunk\n",
+			"root/a.toc":    "",
+		})
+		defer helpers.DeleteFile(t, zipPath)()
+		fetch := func(url, tag, dest string) error {
+			return ErrUnsupportedExternal
+		}
+
+		err := InstallSourceArchive(zipPath, dir, "addon", fetch)
+
+		var missing *MissingExternalsError
+		assert.ErrorAs(t, err, &missing)
+		assert.Equal(t, []string{"svn://repos.wowace.com/wow/libstub/trunk"}, missing.URLs)
+		assert.FileExists(t, filepath.Join(dir, "addon", "a.toc"))
+		assert.NoDirExists(t, filepath.Join(dir, "addon", "Libs", "LibStub"))
+	})
+	t.Run("no fetcher", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		zipPath := writeZip(t, "", map[string]string{
+			"root/.pkgmeta": "externals:\n  Libs/LibStub: https://github.com/owner/libstub\n  Libs/LibFoo: git://example.com/libfoo\n",
+			"root/a.toc":    "",
+		})
+		defer helpers.DeleteFile(t, zipPath)()
+
+		err := InstallSourceArchive(zipPath, dir, "addon", nil)
+
+		var missing *MissingExternalsError
+		assert.ErrorAs(t, err, &missing)
+		assert.Equal(t, []string{"git://example.com/libfoo", "https://github.com/owner/libstub"}, missing.URLs)
+		assert.FileExists(t, filepath.Join(dir, "addon", "a.toc"))
+	})
+	t.Run("failing external", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		zipPath := writeZip(t, "", map[string]string{
+			"root/.pkgmeta": "externals:\n  Libs/LibFoo: https://github.com/owner/libfoo\n",
+			"root/a.toc":    "",
+		})
+		defer helpers.DeleteFile(t, zipPath)()
+		fetch := func(url, tag, dest string) error {
+			return assert.AnError
+		}
+
+		err := InstallSourceArchive(zipPath, dir, "addon", fetch)
+
+		assert.Error(t, err)
+		assert.NoDirExists(t, filepath.Join(dir, "addon"))
+	})
+}
+
+func Test_isIgnored(t *testing.T) {
+	meta := &PkgMeta{Ignore: []string{"README.md", "Docs/", "*.psd", "Libs/*/Tests"}}
+
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{rel: "README.md", want: true},
+		{rel: "Docs/index.md", want: true},
+		{rel: "logo.psd", want: true},
+		{rel: "Media/icon.psd", want: false},
+		{rel: "Libs/LibFoo/Tests", want: true},
+		{rel: "Libs/LibFoo/Tests/a.lua", want: true},
+		{rel: "Libs/LibFoo/LibFoo.lua", want: false},
+		{rel: "Libs/Tests", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			assert.Equal(t, tt.want, meta.isIgnored(tt.rel))
+		})
+	}
+}
+
+func TestExtractExternal(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	zipPath := writeZip(t, "", map[string]string{
+		"owner-lib-123/Lib.lua":   "",
+		"owner-lib-123/.pkgmeta":  "",
+		"owner-lib-123/sub/a.lua": "",
+	})
+	defer helpers.DeleteFile(t, zipPath)()
+	dest := filepath.Join(dir, "Libs", "Lib")
+
+	err := ExtractExternal(zipPath, dest)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dest, "Lib.lua"))
+	assert.FileExists(t, filepath.Join(dest, "sub", "a.lua"))
+	assert.NoFileExists(t, filepath.Join(dest, ".pkgmeta"))
+	entries, _ := os.ReadDir(filepath.Join(dir, "Libs"))
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,169 @@
+// Package plugin implements update sources backed by external executables.
+//
+// The executable is started once per operation. It receives a single JSON request
+// on stdin and has to write a single JSON response to stdout:
+//
+//	{"operation": "regex"}                          -> {"regex": "^https://example\\.com/.+$"}
+//	{"operation": "version", "url": "<addon url>"}  -> {"version": "1.2.3"}
+//	{"operation": "download", "url": "<addon url>"} -> {"download_url": "https://..."} or {"archive_path": "/path/to/addon.zip"}
+//
+// Failures are reported with {"error": "message"} or a non-zero exit code.
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/updater/sources"
+	"github.com/unly/wow-addon-updater/util"
+)
+
+const (
+	operationRegex    = "regex"
+	operationVersion  = "version"
+	operationDownload = "download"
+)
+
+// Request is sent to the plugin on stdin
+type Request struct {
+	Operation string `json:"operation"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Response is expected from the plugin on stdout
+type Response struct {
+	Error       string `json:"error,omitempty"`
+	Regex       string `json:"regex,omitempty"`
+	Version     string `json:"version,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"`
+	ArchivePath string `json:"archive_path,omitempty"`
+}
+
+// source is a source delegating to an external executable
+type source struct {
+	downloader sources.Downloader
+	name       string
+	command    string
+	args       []string
+	regex      *regexp.Regexp
+}
+
+// New returns a new update source for the given plugin executable.
+// The plugin is asked for the regular expression of its supported URLs right away.
+func New(client *http.Client, conf config.PluginConfig) (updater.UpdateSource, error) {
+	if conf.Name == "" || conf.Command == "" {
+		return nil, errors.New("a plugin source requires a name and a command")
+	}
+
+	s := &source{
+		name:    conf.Name,
+		command: conf.Command,
+		args:    conf.Args,
+	}
+
+	resp, err := s.call(Request{Operation: operationRegex})
+	if err != nil {
+		return nil, err
+	}
+
+	s.regex, err = regexp.Compile(resp.Regex)
+	if err != nil || resp.Regex == "" {
+		return nil, fmt.Errorf("plugin %s returned an invalid regex %q", conf.Name, resp.Regex)
+	}
+
+	s.downloader, err = sources.NewDownloader(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *source) GetURLRegex() *regexp.Regexp {
+	return s.regex
+}
+
+// GetAddonID returns the host and the path of the addon URL, e.g. example.com/addons/1
+func (s *source) GetAddonID(addonURL string) (string, error) {
+	return sources.URLID(addonURL)
+}
+
+// GetLatestVersion returns the version reported by the plugin
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	resp, err := s.call(Request{Operation: operationVersion, URL: addonURL})
+	if err != nil {
+		return "", err
+	}
+	if resp.Version == "" {
+		return "", fmt.Errorf("plugin %s returned no version for: %s", s.name, addonURL)
+	}
+
+	return resp.Version, nil
+}
+
+// DownloadAddon downloads the archive from the URL reported by the plugin or takes the
+// archive the plugin created and unzips it to the given directory
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	resp, err := s.call(Request{Operation: operationDownload, URL: addonURL})
+	if err != nil {
+		return err
+	}
+
+	zipPath := resp.ArchivePath
+	if zipPath == "" {
+		if resp.DownloadURL == "" {
+			return fmt.Errorf("plugin %s returned neither a download url nor an archive path for: %s", s.name, addonURL)
+		}
+
+		zipPath, err = s.downloader.DownloadZip(resp.DownloadURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = util.Unzip(zipPath, dir)
+	return err
+}
+
+// call runs the plugin with the request and decodes its response
+func (s *source) call(req Request) (Response, error) {
+	var resp Response
+
+	in, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	cmd := exec.Command(s.command, s.args...)
+	cmd.Stdin = bytes.NewReader(in)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return resp, fmt.Errorf("plugin %s failed on %s: %v: %s", s.name, req.Operation, err, strings.TrimSpace(stderr.String()))
+	}
+
+	err = json.Unmarshal(out, &resp)
+	if err != nil {
+		return resp, fmt.Errorf("plugin %s returned an invalid response on %s: %v", s.name, req.Operation, err)
+	}
+
+	if resp.Error != "" {
+		return resp, fmt.Errorf("plugin %s failed on %s: %s", s.name, req.Operation, resp.Error)
+	}
+
+	return resp, nil
+}
+
+func (s *source) Close() error {
+	return s.downloader.Close()
+}
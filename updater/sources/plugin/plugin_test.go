@@ -0,0 +1,232 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+const helperEnv = "WOW_UPDATER_PLUGIN_RESPONSES"
+
+// TestHelperProcess is not a real test. It acts as plugin executable answering
+// the operations with the responses given by the environment.
+func TestHelperProcess(t *testing.T) {
+	raw, ok := os.LookupEnv(helperEnv)
+	if !ok {
+		return
+	}
+	defer os.Exit(0)
+
+	responses := make(map[string]string)
+	if err := json.Unmarshal([]byte(raw), &responses); err != nil {
+		os.Exit(2)
+	}
+
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		os.Exit(2)
+	}
+
+	resp, ok := responses[req.Operation]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown operation %s", req.Operation)
+		os.Exit(1)
+	}
+	fmt.Print(resp)
+}
+
+func pluginConfig(t *testing.T, responses map[string]string) config.PluginConfig {
+	t.Helper()
+	raw, err := json.Marshal(responses)
+	if err != nil {
+		assert.FailNow(t, "failed to marshal responses", err)
+	}
+	t.Setenv(helperEnv, string(raw))
+
+	return config.PluginConfig{
+		Name:    "test",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperProcess"},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "valid regex",
+			responses: map[string]string{"regex": `{"regex": "^https://example\\.com/.+$"}`},
+		},
+		{
+			name:      "invalid regex",
+			responses: map[string]string{"regex": `{"regex": "("}`},
+			wantErr:   true,
+		},
+		{
+			name:      "empty regex",
+			responses: map[string]string{"regex": `{}`},
+			wantErr:   true,
+		},
+		{
+			name:      "error response",
+			responses: map[string]string{"regex": `{"error": "not configured"}`},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid response",
+			responses: map[string]string{"regex": `regex`},
+			wantErr:   true,
+		},
+		{
+			name:      "failing plugin",
+			responses: map[string]string{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(nil, pluginConfig(t, tt.responses))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, s.GetURLRegex().MatchString("https://example.com/addon"))
+				_ = s.Close()
+			}
+		})
+	}
+	t.Run("missing command", func(t *testing.T) {
+		_, err := New(nil, config.PluginConfig{Name: "test"})
+
+		assert.Error(t, err)
+	})
+	t.Run("not existing command", func(t *testing.T) {
+		_, err := New(nil, config.PluginConfig{Name: "test", Command: "not-existing-plugin"})
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_GetLatestVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "version",
+			response: `{"version": "1.2.3"}`,
+			want:     "1.2.3",
+		},
+		{
+			name:     "empty version",
+			response: `{"version": ""}`,
+			wantErr:  true,
+		},
+		{
+			name:     "error response",
+			response: `{"error": "not found"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(nil, pluginConfig(t, map[string]string{
+				"regex":   `{"regex": ".+"}`,
+				"version": tt.response,
+			}))
+			if err != nil {
+				assert.FailNow(t, "failed to create plugin source", err)
+			}
+			defer s.Close()
+
+			got, err := s.GetLatestVersion("https://example.com/addon")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_DownloadAddon(t *testing.T) {
+	archive, err := filepath.Abs(filepath.Join("..", "_tests", "archive1.zip"))
+	if err != nil {
+		assert.FailNow(t, "failed to get archive path", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/addon.zip", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, archive)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	archiveJSON, _ := json.Marshal(archive)
+
+	tests := []struct {
+		name     string
+		download string
+		wantErr  bool
+	}{
+		{
+			name:     "download url",
+			download: fmt.Sprintf(`{"download_url": "%s/addon.zip"}`, server.URL),
+		},
+		{
+			name:     "archive path",
+			download: fmt.Sprintf(`{"archive_path": %s}`, archiveJSON),
+		},
+		{
+			name:     "empty response",
+			download: `{}`,
+			wantErr:  true,
+		},
+		{
+			name:     "failing download",
+			download: fmt.Sprintf(`{"download_url": "%s/missing.zip"}`, server.URL),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(nil, pluginConfig(t, map[string]string{
+				"regex":    `{"regex": ".+"}`,
+				"download": tt.download,
+			}))
+			if err != nil {
+				assert.FailNow(t, "failed to create plugin source", err)
+			}
+			defer s.Close()
+			dir := helpers.TempDir(t)
+			defer helpers.DeleteDir(t, dir)()
+
+			err = s.DownloadAddon("https://example.com/addon", dir)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.FileExists(t, filepath.Join(dir, "root", "a.txt"))
+			}
+		})
+	}
+}
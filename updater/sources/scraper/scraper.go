@@ -0,0 +1,199 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/updater"
+	"github.com/unly/wow-addon-updater/updater/sources"
+	"github.com/unly/wow-addon-updater/util"
+)
+
+// source is a user-defined source reading addon versions and download links from HTML pages
+type source struct {
+	downloader     sources.Downloader
+	client         *http.Client
+	name           string
+	regex          *regexp.Regexp
+	version        string
+	versionPattern *regexp.Regexp
+	download       string
+	downloadURL    *template.Template
+}
+
+// New returns a new update source scraping the addon pages as defined by the given config.
+// The download URL is either taken from the href attribute of the download selector or
+// rendered from the download template. The template has access to the named groups of the
+// URL regex as well as .URL and .Version, e.g. https://example.com/download/{{.id}}.
+func New(client *http.Client, conf config.ScraperConfig) (updater.UpdateSource, error) {
+	if conf.Name == "" {
+		return nil, errors.New("the scraper source has no name")
+	}
+	if conf.URL == "" || conf.Version == "" {
+		return nil, fmt.Errorf("the scraper source %s requires an url regex and a version selector", conf.Name)
+	}
+	if (conf.Download == "") == (conf.DownloadTemplate == "") {
+		return nil, fmt.Errorf("the scraper source %s requires either a download selector or a download template", conf.Name)
+	}
+
+	regex, err := regexp.Compile(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url regex of scraper source %s: %v", conf.Name, err)
+	}
+
+	var versionPattern *regexp.Regexp
+	if conf.VersionPattern != "" {
+		versionPattern, err = regexp.Compile(conf.VersionPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version pattern of scraper source %s: %v", conf.Name, err)
+		}
+		if versionPattern.NumSubexp() < 1 {
+			return nil, fmt.Errorf("the version pattern of scraper source %s has no group", conf.Name)
+		}
+	}
+
+	var downloadURL *template.Template
+	if conf.DownloadTemplate != "" {
+		downloadURL, err = template.New(conf.Name).Option("missingkey=error").Parse(conf.DownloadTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid download template of scraper source %s: %v", conf.Name, err)
+		}
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	d, err := sources.NewDownloader(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &source{
+		downloader:     d,
+		client:         client,
+		name:           conf.Name,
+		regex:          regex,
+		version:        conf.Version,
+		versionPattern: versionPattern,
+		download:       conf.Download,
+		downloadURL:    downloadURL,
+	}, nil
+}
+
+func (s *source) GetURLRegex() *regexp.Regexp {
+	return s.regex
+}
+
+// GetAddonID returns the host and the path of the addon page, e.g. example.com/addons/1
+func (s *source) GetAddonID(addonURL string) (string, error) {
+	return sources.URLID(addonURL)
+}
+
+// GetLatestVersion returns the text of the version element of the addon page
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	doc, err := util.GetHTMLPage(s.client, addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	return s.getVersion(doc, addonURL)
+}
+
+// DownloadAddon downloads and unzip the addon from the scraped or rendered download URL
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	doc, err := util.GetHTMLPage(s.client, addonURL)
+	if err != nil {
+		return err
+	}
+
+	link, err := s.getDownloadURL(doc, addonURL)
+	if err != nil {
+		return err
+	}
+
+	zipPath, err := s.downloader.DownloadZip(link)
+	if err != nil {
+		return err
+	}
+
+	_, err = util.Unzip(zipPath, dir)
+	return err
+}
+
+func (s *source) getVersion(doc *goquery.Document, addonURL string) (string, error) {
+	version := strings.TrimSpace(doc.Find(s.version).First().Text())
+	if s.versionPattern != nil {
+		match := s.versionPattern.FindStringSubmatch(version)
+		if match == nil {
+			return "", fmt.Errorf("the version pattern of %s does not match %q for: %s", s.name, version, addonURL)
+		}
+		version = strings.TrimSpace(match[1])
+	}
+
+	if version == "" {
+		return "", fmt.Errorf("failed to find a version for: %s", addonURL)
+	}
+
+	return version, nil
+}
+
+func (s *source) getDownloadURL(doc *goquery.Document, addonURL string) (string, error) {
+	if s.downloadURL == nil {
+		href, ok := doc.Find(s.download).First().Attr("href")
+		if !ok || href == "" {
+			return "", fmt.Errorf("failed to find download link for: %s", addonURL)
+		}
+
+		return resolve(addonURL, href)
+	}
+
+	data := map[string]string{
+		"URL": addonURL,
+	}
+	match := s.regex.FindStringSubmatch(addonURL)
+	for i, name := range s.regex.SubexpNames() {
+		if name != "" && i < len(match) {
+			data[name] = match[i]
+		}
+	}
+	if version, err := s.getVersion(doc, addonURL); err == nil {
+		data["Version"] = version
+	}
+
+	var buf bytes.Buffer
+	err := s.downloadURL.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render the download url for %s: %v", addonURL, err)
+	}
+
+	return resolve(addonURL, buf.String())
+}
+
+// resolve resolves a possibly relative link against the page URL
+func resolve(pageURL, link string) (string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (s *source) Close() error {
+	return s.downloader.Close()
+}
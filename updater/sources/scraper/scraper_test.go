@@ -0,0 +1,233 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/util/tests/helpers"
+)
+
+const addonPage = `
+<!DOCTYPE html>
+<html>
+<head><title>My Addon</title></head>
+<body>
+	<div class="info"><span id="version">Version: %s</span></div>
+	<a class="download" href="%s">Download</a>
+</body>
+</html>
+`
+
+func newScraperServer(t *testing.T, version, link string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/addons/42", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, addonPage, version, link)
+	})
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		content, err := os.ReadFile(filepath.Join("..", "_tests", "archive1.zip"))
+		assert.NoError(t, err)
+		_, _ = w.Write(content)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newScraperSource(t *testing.T, conf config.ScraperConfig) *source {
+	t.Helper()
+	s, err := New(nil, conf)
+	if err != nil {
+		assert.FailNow(t, "failed to create scraper source", err)
+	}
+
+	return s.(*source)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.ScraperConfig
+		wantErr bool
+	}{
+		{
+			name: "download selector",
+			conf: config.ScraperConfig{Name: "site", URL: `^https://example\.com/.+$`, Version: "#version", Download: "a"},
+		},
+		{
+			name: "download template",
+			conf: config.ScraperConfig{Name: "site", URL: `^https://example\.com/(?P<id>\d+)$`, Version: "#version", DownloadTemplate: "https://example.com/dl/{{.id}}"},
+		},
+		{
+			name:    "no name",
+			conf:    config.ScraperConfig{URL: `.+`, Version: "#version", Download: "a"},
+			wantErr: true,
+		},
+		{
+			name:    "no version selector",
+			conf:    config.ScraperConfig{Name: "site", URL: `.+`, Download: "a"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid url regex",
+			conf:    config.ScraperConfig{Name: "site", URL: `(`, Version: "#version", Download: "a"},
+			wantErr: true,
+		},
+		{
+			name:    "version pattern without group",
+			conf:    config.ScraperConfig{Name: "site", URL: `.+`, Version: "#version", VersionPattern: "Version: .+", Download: "a"},
+			wantErr: true,
+		},
+		{
+			name:    "download selector and template",
+			conf:    config.ScraperConfig{Name: "site", URL: `.+`, Version: "#version", Download: "a", DownloadTemplate: "{{.URL}}"},
+			wantErr: true,
+		},
+		{
+			name:    "no download",
+			conf:    config.ScraperConfig{Name: "site", URL: `.+`, Version: "#version"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			conf:    config.ScraperConfig{Name: "site", URL: `.+`, Version: "#version", DownloadTemplate: "{{.URL"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(nil, tt.conf)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				_ = s.Close()
+			}
+		})
+	}
+}
+
+func Test_GetLatestVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "element text",
+			version: "1.2.3",
+			want:    "Version: 1.2.3",
+		},
+		{
+			name:    "version pattern",
+			pattern: `Version: (\S+)`,
+			version: "1.2.3",
+			want:    "1.2.3",
+		},
+		{
+			name:    "version pattern mismatch",
+			pattern: `v(\d+)`,
+			version: "1.2.3",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScraperServer(t, tt.version, "")
+			defer server.Close()
+			s := newScraperSource(t, config.ScraperConfig{
+				Name:           "site",
+				URL:            `.+`,
+				Version:        "#version",
+				VersionPattern: tt.pattern,
+				Download:       "a.download",
+			})
+			defer s.Close()
+
+			got, err := s.GetLatestVersion(server.URL + "/addons/42")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+	t.Run("missing version element", func(t *testing.T) {
+		server := newScraperServer(t, "", "")
+		defer server.Close()
+		s := newScraperSource(t, config.ScraperConfig{Name: "site", URL: `.+`, Version: ".missing", Download: "a"})
+		defer s.Close()
+
+		_, err := s.GetLatestVersion(server.URL + "/addons/42")
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_DownloadAddon(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    string
+		conf    config.ScraperConfig
+		wantErr bool
+	}{
+		{
+			name: "relative download link",
+			link: "/files/addon.zip",
+			conf: config.ScraperConfig{Name: "site", URL: `.+`, Version: "#version", Download: "a.download"},
+		},
+		{
+			name: "download template",
+			conf: config.ScraperConfig{
+				Name:             "site",
+				URL:              `/addons/(?P<id>\d+)$`,
+				Version:          "#version",
+				VersionPattern:   `Version: (\S+)`,
+				DownloadTemplate: "/files/{{.id}}-{{.Version}}.zip",
+			},
+		},
+		{
+			name:    "missing download link",
+			conf:    config.ScraperConfig{Name: "site", URL: `.+`, Version: "#version", Download: "a.missing"},
+			wantErr: true,
+		},
+		{
+			name:    "missing template data",
+			conf:    config.ScraperConfig{Name: "site", URL: `.+`, Version: "#version", DownloadTemplate: "/files/{{.id}}.zip"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScraperServer(t, "1.0", tt.link)
+			defer server.Close()
+			s := newScraperSource(t, tt.conf)
+			defer s.Close()
+			dir := helpers.TempDir(t)
+			defer helpers.DeleteDir(t, dir)()
+
+			err := s.DownloadAddon(server.URL+"/addons/42", dir)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.FileExists(t, filepath.Join(dir, "root", "a.txt"))
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package tukui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/unly/go-tukui"
+)
+
+const apiURL = "https://www.tukui.org/api.php"
+
+// tbcClient queries the classic TBC addons of the tukui.org API
+// which are not covered by the go-tukui client
+type tbcClient struct {
+	client *http.Client
+	url    string
+}
+
+func newTBCClient(client *http.Client) *tbcClient {
+	return &tbcClient{
+		client: client,
+		url:    apiURL,
+	}
+}
+
+func (c *tbcClient) GetTukUI() (tukui.Addon, *http.Response, error) {
+	return c.GetAddon(1)
+}
+
+func (c *tbcClient) GetElvUI() (tukui.Addon, *http.Response, error) {
+	return c.GetAddon(2)
+}
+
+func (c *tbcClient) GetAddon(id int) (tukui.Addon, *http.Response, error) {
+	var addon tukui.Addon
+
+	resp, err := c.query(fmt.Sprintf("classic-tbc-addon=%d", id), &addon)
+
+	return addon, resp, err
+}
+
+func (c *tbcClient) GetAddons() ([]tukui.Addon, *http.Response, error) {
+	var addons []tukui.Addon
+
+	resp, err := c.query("classic-tbc-addons=all", &addons)
+
+	return addons, resp, err
+}
+
+func (c *tbcClient) query(query string, data interface{}) (*http.Response, error) {
+	resp, err := c.client.Get(c.url + "?" + query)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, nil
+	}
+
+	return resp, json.NewDecoder(resp.Body).Decode(data)
+}
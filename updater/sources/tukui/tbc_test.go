@@ -0,0 +1,58 @@
+package tukui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTBCServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RawQuery {
+		case "classic-tbc-addon=2":
+			_, _ = w.Write([]byte(`{"id": "2", "name": "ElvUI", "version": "2.1", "url": "https://www.tukui.org/classic-tbc-addons.php?download=2"}`))
+		case "classic-tbc-addons=all":
+			_, _ = w.Write([]byte(`[{"id": "1", "version": "1.0"}, {"id": "2", "version": "2.1"}]`))
+		case "classic-tbc-addon=3":
+			_, _ = w.Write([]byte(`invalid`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func Test_tbcClient_GetAddon(t *testing.T) {
+	server := newTBCServer(t)
+	defer server.Close()
+	c := newTBCClient(http.DefaultClient)
+	c.url = server.URL
+
+	t.Run("elvui", func(t *testing.T) {
+		addon, resp, err := c.GetElvUI()
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2.1", *addon.Version)
+		assert.Equal(t, "https://www.tukui.org/classic-tbc-addons.php?download=2", *addon.URL)
+	})
+	t.Run("not existing addon", func(t *testing.T) {
+		_, resp, err := c.GetTukUI()
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("invalid response", func(t *testing.T) {
+		_, _, err := c.GetAddon(3)
+
+		assert.Error(t, err)
+	})
+	t.Run("all addons", func(t *testing.T) {
+		addons, _, err := c.GetAddons()
+
+		assert.NoError(t, err)
+		assert.Len(t, addons, 2)
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/unly/go-tukui"
 
+	"github.com/unly/wow-addon-updater/game"
 	"github.com/unly/wow-addon-updater/updater"
 	"github.com/unly/wow-addon-updater/updater/sources"
 	"github.com/unly/wow-addon-updater/util"
@@ -18,6 +19,10 @@ var (
 	regex   = regexp.MustCompile(`^(https?://)?(www\.)?tukui\.org/((classic-(tbc-)?)?addons\.php\?id=[0-9]+)|(download\.php\?ui=(tukui|elvui))$`)
 	idRegex = regexp.MustCompile(`id=[0-9]+`)
 	uiRegex = regexp.MustCompile(`ui=.+`)
+	// short reference of an addon id with an optional flavor
+	refRegex = regexp.MustCompile(`^(?:(classic|classic-tbc):)?([0-9]+)$`)
+	// flavor and id of the addon pages
+	pageRegex = regexp.MustCompile(`/(?:(classic|classic-tbc)-)?addons\.php\?id=([0-9]+)`)
 )
 
 //go:generate go run github.com/vektra/mockery/v2 --case=underscore  --name=tukuiAPI --structname=MockTukUIAPI
@@ -30,7 +35,18 @@ type tukUISource struct {
 	downloader sources.Downloader
 	client     *http.Client
 	classic    tukuiAPI
+	tbc        tukuiAPI
 	retail     tukuiAPI
+	// flavor of the installation the source is used for
+	flavor game.Flavor
+	// addon lists of the API per flavor, fetched once for the run
+	lists map[game.Flavor]*addonList
+}
+
+// addonList is the result of fetching all addons of a flavor from the API
+type addonList struct {
+	addons map[string]tukui.Addon
+	err    error
 }
 
 // New returns a pointer to a newly created TukUISource.
@@ -49,14 +65,85 @@ func New(client *http.Client) (updater.UpdateSource, error) {
 		downloader: d,
 		client:     client,
 		classic:    tukClient.ClassicAddons,
+		tbc:        newTBCClient(client),
 		retail:     tukClient.RetailAddons,
+		flavor:     game.Retail,
+		lists:      make(map[game.Flavor]*addonList),
 	}, nil
 }
 
+// ForFlavor returns a copy of the source downloading the UIs for the given flavor
+func (t *tukUISource) ForFlavor(flavor game.Flavor) updater.UpdateSource {
+	flavored := *t
+	flavored.flavor = flavor
+
+	return &flavored
+}
+
+// api returns the API client of the flavor the source is used for
+func (t *tukUISource) api() tukuiAPI {
+	return t.apiOf(t.flavor)
+}
+
+// apiOf returns the API client of the given flavor
+func (t *tukUISource) apiOf(flavor game.Flavor) tukuiAPI {
+	switch flavor {
+	case game.Classic:
+		return t.classic
+	case game.ClassicTBC:
+		return t.tbc
+	default:
+		return t.retail
+	}
+}
+
 func (tukUISource) GetURLRegex() *regexp.Regexp {
 	return regex
 }
 
+// GetAddonID returns the short reference of the addon, i.e. the name of the UI, e.g. tukui:elvui,
+// or the addon id prefixed by its flavor if not retail, e.g. tukui:12 or tukui:classic:12
+func (tukUISource) GetAddonID(addonURL string) (string, error) {
+	if match := pageRegex.FindStringSubmatch(addonURL); match != nil {
+		if match[1] == "" {
+			return "tukui:" + match[2], nil
+		}
+		return "tukui:" + match[1] + ":" + match[2], nil
+	}
+
+	ui := strings.TrimPrefix(uiRegex.FindString(addonURL), "ui=")
+	if ui == "tukui" || ui == "elvui" {
+		return "tukui:" + ui, nil
+	}
+
+	return "", fmt.Errorf("tukui.org url %s is not supported", addonURL)
+}
+
+func (tukUISource) ReferencePrefix() string {
+	return "tukui"
+}
+
+// ExpandReference returns the addon URL for a short reference of a UI, e.g. elvui,
+// or an addon id with an optional flavor, e.g. 12, classic:12 or classic-tbc:12
+func (tukUISource) ExpandReference(ref string) (string, error) {
+	switch ref {
+	case "tukui", "elvui":
+		return "https://www.tukui.org/download.php?ui=" + ref, nil
+	}
+
+	match := refRegex.FindStringSubmatch(ref)
+	if match == nil {
+		return "", errors.New("expected tukui, elvui or an addon id with an optional classic or classic-tbc flavor")
+	}
+
+	page := "addons.php"
+	if match[1] != "" {
+		page = match[1] + "-" + page
+	}
+
+	return fmt.Sprintf("https://www.tukui.org/%s?id=%s", page, match[2]), nil
+}
+
 // GetLatestVersion returns the latest version for the given addon URL
 func (t *tukUISource) GetLatestVersion(addonURL string) (string, error) {
 	tukuiAddon, err := t.getAddon(addonURL)
@@ -94,17 +181,76 @@ func (t *tukUISource) getUIAddon(url string) (tukui.Addon, error) {
 
 	switch string(uiRunes[3:]) {
 	case "tukui":
-		ui, resp, err := t.retail.GetTukUI()
+		ui, resp, err := t.api().GetTukUI()
 		return ui, util.CheckHTTPResponse(resp, err)
 	case "elvui":
-		ui, resp, err := t.retail.GetElvUI()
+		ui, resp, err := t.api().GetElvUI()
 		return ui, util.CheckHTTPResponse(resp, err)
 	default:
 		return tukui.Addon{}, fmt.Errorf("given tukui.org ui addon link %s is not supported", url)
 	}
 }
 
+// getRegularAddon returns the addon of the given addon page from the API of the flavor of the page.
+// Falls back to scraping the addon page if the API is not available or does not know the addon.
 func (t *tukUISource) getRegularAddon(url string) (tukui.Addon, error) {
+	addon, err := t.getAPIAddon(url)
+	if err == nil {
+		return addon, nil
+	}
+
+	return t.scrapeAddon(url)
+}
+
+func (t *tukUISource) getAPIAddon(url string) (tukui.Addon, error) {
+	match := pageRegex.FindStringSubmatch(url)
+	if match == nil {
+		return tukui.Addon{}, fmt.Errorf("failed to find the addon id in: %s", url)
+	}
+
+	flavor := game.Retail
+	if match[1] != "" {
+		flavor = game.Flavor(match[1])
+	}
+
+	addons, err := t.getAddonList(flavor)
+	if err != nil {
+		return tukui.Addon{}, err
+	}
+
+	addon, ok := addons[match[2]]
+	if !ok || addon.Version == nil || addon.URL == nil {
+		return tukui.Addon{}, fmt.Errorf("the api does not provide the addon %s", url)
+	}
+
+	return addon, nil
+}
+
+// getAddonList returns the addons of the given flavor by their id.
+// The API is queried once per flavor.
+func (t *tukUISource) getAddonList(flavor game.Flavor) (map[string]tukui.Addon, error) {
+	list, ok := t.lists[flavor]
+	if ok {
+		return list.addons, list.err
+	}
+
+	list = &addonList{
+		addons: make(map[string]tukui.Addon),
+	}
+	addons, resp, err := t.apiOf(flavor).GetAddons()
+	list.err = util.CheckHTTPResponse(resp, err)
+	for _, addon := range addons {
+		if addon.Id != nil {
+			list.addons[*addon.Id] = addon
+		}
+	}
+	t.lists[flavor] = list
+
+	return list.addons, list.err
+}
+
+// scrapeAddon reads the version from the addon page and derives the download URL from the page URL
+func (t *tukUISource) scrapeAddon(url string) (tukui.Addon, error) {
 	addon := tukui.Addon{}
 
 	doc, err := util.GetHTMLPage(t.client, url)
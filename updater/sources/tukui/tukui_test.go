@@ -1,6 +1,7 @@
 package tukui
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/unly/go-tukui"
 
+	"github.com/unly/wow-addon-updater/game"
 	"github.com/unly/wow-addon-updater/updater/sources/tukui/mocks"
 	"github.com/unly/wow-addon-updater/util/tests/helpers"
 )
@@ -297,9 +299,20 @@ func newTukUISource(t *testing.T, client *http.Client) *tukUISource {
 	if !ok {
 		t.FailNow()
 	}
+	// do not query the real API, regular addons are scraped unless a test sets up the API
+	source.retail = unavailableAPI()
+	source.classic = unavailableAPI()
+	source.tbc = unavailableAPI()
 	return source
 }
 
+func unavailableAPI() *mocks.MockTukUIAPI {
+	m := &mocks.MockTukUIAPI{}
+	m.On("GetAddons").Return([]tukui.Addon(nil), nil, errors.New("api not available"))
+
+	return m
+}
+
 func getUIAddonURLs(t *testing.T) []func() *addonTest {
 	return []func() *addonTest{
 		func() *addonTest {
@@ -549,3 +562,167 @@ func getIDAddonURLs(t *testing.T) []func() *addonTest {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func Test_ExpandReference_TukUI(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "elvui", want: "https://www.tukui.org/download.php?ui=elvui"},
+		{ref: "tukui", want: "https://www.tukui.org/download.php?ui=tukui"},
+		{ref: "12", want: "https://www.tukui.org/addons.php?id=12"},
+		{ref: "classic:12", want: "https://www.tukui.org/classic-addons.php?id=12"},
+		{ref: "classic-tbc:12", want: "https://www.tukui.org/classic-tbc-addons.php?id=12"},
+		{ref: "retail:12", wantErr: true},
+		{ref: "classic:", wantErr: true},
+		{ref: "otherui", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := tukUISource{}.ExpandReference(tt.ref)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.True(t, regex.MatchString(got))
+			}
+		})
+	}
+}
+
+func Test_GetAddonID_TukUI(t *testing.T) {
+	tests := []struct {
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{addonURL: "https://www.tukui.org/download.php?ui=elvui", want: "tukui:elvui"},
+		{addonURL: "http://tukui.org/download.php?ui=tukui", want: "tukui:tukui"},
+		{addonURL: "https://www.tukui.org/addons.php?id=12", want: "tukui:12"},
+		{addonURL: "tukui.org/classic-addons.php?id=12", want: "tukui:classic:12"},
+		{addonURL: "https://www.tukui.org/classic-tbc-addons.php?id=12", want: "tukui:classic-tbc:12"},
+		{addonURL: "https://www.tukui.org/download.php?ui=other", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			got, err := tukUISource{}.GetAddonID(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_ForFlavor_TukUI(t *testing.T) {
+	tests := []struct {
+		flavor game.Flavor
+		want   string
+	}{
+		{flavor: game.Retail, want: "retail"},
+		{flavor: game.Classic, want: "classic"},
+		{flavor: game.ClassicTBC, want: "tbc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.flavor), func(t *testing.T) {
+			s := newTukUISource(t, nil)
+			defer s.Close()
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+			}
+			apis := make(map[string]*mocks.MockTukUIAPI)
+			for _, name := range []string{"retail", "classic", "tbc"} {
+				m := &mocks.MockTukUIAPI{}
+				m.On("GetElvUI").Return(tukui.Addon{Version: stringPtr(name)}, resp, nil)
+				apis[name] = m
+			}
+			s.retail, s.classic, s.tbc = apis["retail"], apis["classic"], apis["tbc"]
+
+			actual, err := s.ForFlavor(tt.flavor).GetLatestVersion("https://www.tukui.org/download.php?ui=elvui")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, actual)
+			assert.Equal(t, game.Retail, s.flavor)
+		})
+	}
+}
+
+func Test_getRegularAddon_API(t *testing.T) {
+	website := fmt.Sprintf(tukuiAddonPage, "0.9")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/addons.php", func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(website))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+	}
+	addon := tukui.Addon{
+		Id:      stringPtr("12"),
+		Version: stringPtr("1.2.3"),
+		URL:     stringPtr("https://www.tukui.org/addons.php?download=12"),
+	}
+
+	t.Run("addon of the api", func(t *testing.T) {
+		s := newTukUISource(t, nil)
+		defer s.Close()
+		m := &mocks.MockTukUIAPI{}
+		m.On("GetAddons").Return([]tukui.Addon{addon}, resp, nil)
+		s.retail = m
+
+		first, err := s.getRegularAddon(server.URL + "/addons.php?id=12")
+		assert.NoError(t, err)
+		second, err := s.ForFlavor(game.Classic).(*tukUISource).getRegularAddon(server.URL + "/addons.php?id=12")
+		assert.NoError(t, err)
+
+		assert.Equal(t, addon, first)
+		assert.Equal(t, addon, second)
+		m.AssertNumberOfCalls(t, "GetAddons", 1)
+	})
+	t.Run("flavor of the page", func(t *testing.T) {
+		s := newTukUISource(t, nil)
+		defer s.Close()
+		m := &mocks.MockTukUIAPI{}
+		m.On("GetAddons").Return([]tukui.Addon{addon}, resp, nil)
+		s.tbc = m
+
+		actual, err := s.getRegularAddon("https://www.tukui.org/classic-tbc-addons.php?id=12")
+
+		assert.NoError(t, err)
+		assert.Equal(t, addon, actual)
+	})
+	t.Run("addon unknown to the api", func(t *testing.T) {
+		s := newTukUISource(t, nil)
+		defer s.Close()
+		m := &mocks.MockTukUIAPI{}
+		m.On("GetAddons").Return([]tukui.Addon{addon}, resp, nil)
+		s.retail = m
+
+		actual, err := s.getRegularAddon(server.URL + "/addons.php?id=13")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "0.9", *actual.Version)
+	})
+	t.Run("api failure", func(t *testing.T) {
+		s := newTukUISource(t, nil)
+		defer s.Close()
+
+		actual, err := s.getRegularAddon(server.URL + "/addons.php?id=12")
+		assert.NoError(t, err)
+		_, err = s.getRegularAddon(server.URL + "/addons.php?id=12")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "0.9", *actual.Version)
+		s.retail.(*mocks.MockTukUIAPI).AssertNumberOfCalls(t, "GetAddons", 1)
+	})
+}
@@ -0,0 +1,69 @@
+package wowinterface
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/unly/wow-addon-updater/util"
+)
+
+const apiURL = "https://api.mmoui.com/v3/game/WOW"
+
+// fileDetails is an entry of the filedetails endpoint of the WoWInterface API
+// with the fields used by the source
+type fileDetails struct {
+	Version  string `json:"UIVersion"`
+	MD5      string `json:"UIMD5"`
+	Download string `json:"UIDownload"`
+}
+
+// getFileDetails returns the details of the addon with the given id.
+// The API is queried once per addon and run.
+func (s *source) getFileDetails(id string) (fileDetails, error) {
+	if details, ok := s.details[id]; ok {
+		return details, nil
+	}
+
+	var files []fileDetails
+	err := util.GetJSON(s.client, fmt.Sprintf("%s/filedetails/%s.json", s.apiURL, id), &files)
+	if err != nil {
+		return fileDetails{}, err
+	}
+	if len(files) == 0 || files[0].Download == "" {
+		return fileDetails{}, fmt.Errorf("the api returned no file for the addon id %s", id)
+	}
+	if files[0].Version == "" {
+		return fileDetails{}, fmt.Errorf("the api returned no version for the addon id %s", id)
+	}
+
+	s.details[id] = files[0]
+	return files[0], nil
+}
+
+// checkMD5 compares the MD5 checksum of the file with the expected one if given
+func checkMD5(path, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch of %s: expected md5 %s, got %s", path, expected, actual)
+	}
+
+	return nil
+}
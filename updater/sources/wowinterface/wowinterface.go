@@ -3,16 +3,27 @@ package wowinterface
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 
+	"github.com/PuerkitoBio/goquery"
+
 	"github.com/unly/wow-addon-updater/updater"
 	"github.com/unly/wow-addon-updater/updater/sources"
 	"github.com/unly/wow-addon-updater/util"
 )
 
+const siteURL = "https://www.wowinterface.com"
+
 var (
-	regex = regexp.MustCompile(`^(https?://)?(www\.)?wowinterface\.com/downloads/info.+\.html$`)
+	regex = regexp.MustCompile(`^((https?://)?(www\.)?wowinterface\.com/downloads/(info[0-9]+(-[^/?#]*)?\.html|(fileinfo|getfile)\.php\?\S*|download[0-9]+(-[^/?#]*)?/?)|(wowi:)?[0-9]+)$`)
+	// the numeric id of the addon
+	idRegex = regexp.MustCompile(`^[0-9]+$`)
+	// the addon page, e.g. info24608-Hekili.html
+	infoRegex = regexp.MustCompile(`^/downloads/info([0-9]+)(-[^/]*)?\.html$`)
+	// the download page, e.g. download24608-Hekili
+	downloadRegex = regexp.MustCompile(`^/downloads/download([0-9]+)(-[^/]*)?/?$`)
 )
 
 // source is the source for addons and UIs hosted on wowinterface.com
@@ -20,9 +31,14 @@ type source struct {
 	downloader sources.Downloader
 	client     *http.Client
 	baseURL    string
+	apiURL     string
+	// file details of the API by addon id, fetched once for the run
+	details map[string]fileDetails
+	// optional files of the addon pages by addon id, fetched once for the run
+	optionals map[string][]optionalFile
 }
 
-//New returns a new update source for wowinterface.com
+// New returns a new update source for wowinterface.com
 func New(client *http.Client) (updater.UpdateSource, error) {
 	if client == nil {
 		client = http.DefaultClient
@@ -36,7 +52,10 @@ func New(client *http.Client) (updater.UpdateSource, error) {
 	return &source{
 		downloader: d,
 		client:     client,
-		baseURL:    "https://www.wowinterface.com",
+		baseURL:    siteURL,
+		apiURL:     apiURL,
+		details:    make(map[string]fileDetails),
+		optionals:  make(map[string][]optionalFile),
 	}, nil
 }
 
@@ -44,40 +63,274 @@ func (source) GetURLRegex() *regexp.Regexp {
 	return regex
 }
 
-// GetLatestVersion returns the latest version for the given addon URL
-func (s *source) GetLatestVersion(addonURL string) (string, error) {
-	doc, err := util.GetHTMLPage(s.client, addonURL)
+// GetAddonID returns the short reference of the addon independent of the form of the URL, e.g. wowi:24608.
+// Optional files are identified by the addon id and the id of the file, e.g. wowi:24608/1234.
+func (source) GetAddonID(addonURL string) (string, error) {
+	id, err := parseAddonID(addonURL)
 	if err != nil {
 		return "", err
 	}
 
-	text := doc.Find("#version").Text()
-	if !strings.HasPrefix(text, "Version: ") {
-		return "", fmt.Errorf("failed to find a version tag for: %s", addonURL)
+	if fileID := parseFileID(addonURL); fileID != "" {
+		return "wowi:" + id + "/" + fileID, nil
+	}
+
+	return "wowi:" + id, nil
+}
 
+// parseFileID returns the id of the optional file of a getfile.php?id=24608&aid=1234 URL
+// or an empty string for any other URL
+func parseFileID(addonURL string) string {
+	u, err := url.Parse(addonURL)
+	if err != nil || !strings.HasSuffix(u.Path, "/getfile.php") {
+		return ""
 	}
 
-	return text[9:], nil
+	aid := u.Query().Get("aid")
+	if !idRegex.MatchString(aid) {
+		return ""
+	}
+
+	return aid
 }
 
-// DownloadAddon downloads and unzip the addon from the given URL to the given directory
-func (s *source) DownloadAddon(addonURL, dir string) error {
-	elems := strings.Split(addonURL, "/")
-	if len(elems) == 0 {
-		return fmt.Errorf("no path to extract from: %s", addonURL)
+// parseAddonID returns the numeric id of the addon of an addon page, e.g. info24608-Hekili.html,
+// a fileinfo.php?id=24608 page, a download page, e.g. download24608-Hekili, the short reference wowi:24608
+// or the bare numeric id.
+func parseAddonID(addonURL string) (string, error) {
+	addonURL = strings.TrimSpace(addonURL)
+	if id := strings.TrimPrefix(addonURL, "wowi:"); idRegex.MatchString(id) {
+		return id, nil
+	}
+
+	if !strings.Contains(addonURL, "://") {
+		addonURL = "https://" + addonURL
+	}
+	u, err := url.Parse(addonURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid wowinterface url %s: %v", addonURL, err)
+	}
+	if host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."); host != "wowinterface.com" {
+		return "", fmt.Errorf("the url %s is not on wowinterface.com", addonURL)
+	}
+
+	if u.Path == "/downloads/fileinfo.php" || u.Path == "/downloads/getfile.php" {
+		id := u.Query().Get("id")
+		if !idRegex.MatchString(id) {
+			return "", fmt.Errorf("the url %s has no numeric id parameter", addonURL)
+		}
+		return id, nil
+	}
+
+	for _, r := range []*regexp.Regexp{infoRegex, downloadRegex} {
+		if match := r.FindStringSubmatch(u.Path); match != nil {
+			return match[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("the url %s is neither an addon nor a download page of wowinterface.com", addonURL)
+}
+
+func (source) ReferencePrefix() string {
+	return "wowi"
+}
+
+// ExpandReference returns the addon page URL for a short reference of the addon id
+func (source) ExpandReference(ref string) (string, error) {
+	if !idRegex.MatchString(ref) {
+		return "", fmt.Errorf("expected the numeric addon id")
+	}
+
+	return infoURL(siteURL, ref), nil
+}
+
+func infoURL(baseURL, id string) string {
+	return fmt.Sprintf("%s/downloads/info%s.html", baseURL, id)
+}
+
+func fileURL(baseURL, id, fileID string) string {
+	return fmt.Sprintf("%s/downloads/getfile.php?id=%s&aid=%s", baseURL, id, fileID)
+}
+
+// optionalFile is an additional file listed on the addon page
+type optionalFile struct {
+	id   string
+	name string
+	// the texts of the other cells of its row, i.e. the version and the date of the upload
+	version string
+}
+
+// GetFileURLs returns the URLs of the given files of the addon. The updater.MainFile is the
+// main download, any other file is an optional file of the addon page selected by its name or id.
+func (s *source) GetFileURLs(addonURL string, files []string) ([]string, error) {
+	id, err := parseAddonID(addonURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var optionals []optionalFile
+	urls := make([]string, 0, len(files))
+	for _, file := range files {
+		if file == updater.MainFile {
+			urls = append(urls, addonURL)
+			continue
+		}
+
+		if optionals == nil {
+			optionals, err = s.getOptionalFiles(id)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		optional, ok := findOptionalFile(optionals, file)
+		if !ok {
+			return nil, fmt.Errorf("the addon %s has no optional file %s. available files: %s", addonURL, file, fileNames(optionals))
+		}
+		urls = append(urls, fileURL(siteURL, id, optional.id))
+	}
+
+	return urls, nil
+}
+
+// getOptionalFiles returns the optional files listed on the addon page.
+// The page is fetched once per addon and run.
+func (s *source) getOptionalFiles(id string) ([]optionalFile, error) {
+	if files, ok := s.optionals[id]; ok {
+		return files, nil
+	}
+
+	page, err := url.Parse(infoURL(s.baseURL, id))
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := util.GetHTMLPage(s.client, page.String())
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]optionalFile, 0)
+	doc.Find(`a[href*="getfile.php"]`).Each(func(_ int, link *goquery.Selection) {
+		href, _ := link.Attr("href")
+		ref, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		fileID := parseFileID(page.ResolveReference(ref).String())
+		if fileID == "" {
+			return
+		}
+
+		files = append(files, optionalFile{
+			id:      fileID,
+			name:    strings.TrimSpace(link.Text()),
+			version: rowVersion(link),
+		})
+	})
+
+	s.optionals[id] = files
+	return files, nil
+}
+
+// rowVersion returns the texts of the cells of the table row of the link besides the one
+// of the link itself. These are the version and the date of the upload. The date changes
+// with every upload even if the author keeps the version.
+func rowVersion(link *goquery.Selection) string {
+	cell := link.Closest("td")
+	texts := make([]string, 0)
+	link.Closest("tr").Find("td").Each(func(_ int, td *goquery.Selection) {
+		if cell.Length() > 0 && td.IsSelection(cell) {
+			return
+		}
+		if text := strings.Join(strings.Fields(td.Text()), " "); text != "" {
+			texts = append(texts, text)
+		}
+	})
+
+	return strings.Join(texts, " ")
+}
+
+// getOptionalFile returns the optional file of the given id listed on the addon page
+func (s *source) getOptionalFile(id, fileID string) (optionalFile, error) {
+	files, err := s.getOptionalFiles(id)
+	if err != nil {
+		return optionalFile{}, err
 	}
 
-	name := elems[len(elems)-1]
-	name = name[4 : len(name)-5]
+	file, ok := findOptionalFile(files, fileID)
+	if !ok {
+		return optionalFile{}, fmt.Errorf("the addon %s has no optional file with the id %s", id, fileID)
+	}
+
+	return file, nil
+}
+
+func findOptionalFile(files []optionalFile, file string) (optionalFile, bool) {
+	for _, f := range files {
+		if strings.EqualFold(f.name, file) || f.id == file {
+			return f, true
+		}
+	}
+
+	return optionalFile{}, false
+}
+
+func fileNames(files []optionalFile) string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// GetLatestVersion returns the latest version for the given addon URL from the API.
+// Falls back to the version on the addon page. The versions of optional files are
+// taken from the list of files on the addon page.
+func (s *source) GetLatestVersion(addonURL string) (string, error) {
+	id, err := parseAddonID(addonURL)
+	if err != nil {
+		return "", err
+	}
+
+	if fileID := parseFileID(addonURL); fileID != "" {
+		file, err := s.getOptionalFile(id, fileID)
+		if err != nil {
+			return "", err
+		}
+		if file.version == "" {
+			return "", fmt.Errorf("failed to find the version of the optional file %s of the addon %s", fileID, id)
+		}
+		return file.version, nil
+	}
+
+	if details, err := s.getFileDetails(id); err == nil {
+		return details.Version, nil
+	}
+
+	return s.scrapeVersion(id)
+}
 
-	doc, err := util.GetHTMLPage(s.client, fmt.Sprintf("%s/downloads/download%s", s.baseURL, name))
+// DownloadAddon downloads and unzip the addon from the given URL to the given directory.
+// The download URL and checksum are taken from the API. Falls back to the download page.
+// Optional files are downloaded directly.
+func (s *source) DownloadAddon(addonURL, dir string) error {
+	id, err := parseAddonID(addonURL)
 	if err != nil {
 		return err
 	}
 
-	link, available := doc.Find(".manuallink > a").Attr("href")
-	if !available {
-		return fmt.Errorf("failed to find download link for: %s", addonURL)
+	link, checksum := "", ""
+	if fileID := parseFileID(addonURL); fileID != "" {
+		link = fileURL(s.baseURL, id, fileID)
+	} else if details, err := s.getFileDetails(id); err == nil {
+		link, checksum = details.Download, details.MD5
+	} else {
+		link, err = s.scrapeDownloadURL(id)
+		if err != nil {
+			return err
+		}
 	}
 
 	zipPath, err := s.downloader.DownloadZip(link)
@@ -85,6 +338,11 @@ func (s *source) DownloadAddon(addonURL, dir string) error {
 		return err
 	}
 
+	err = checkMD5(zipPath, checksum)
+	if err != nil {
+		return err
+	}
+
 	_, err = util.Unzip(zipPath, dir)
 	if err != nil {
 		return err
@@ -93,6 +351,37 @@ func (s *source) DownloadAddon(addonURL, dir string) error {
 	return nil
 }
 
+func (s *source) scrapeVersion(id string) (string, error) {
+	page := infoURL(s.baseURL, id)
+	doc, err := util.GetHTMLPage(s.client, page)
+	if err != nil {
+		return "", err
+	}
+
+	text := doc.Find("#version").Text()
+	if !strings.HasPrefix(text, "Version: ") {
+		return "", fmt.Errorf("failed to find a version tag for: %s", page)
+
+	}
+
+	return text[9:], nil
+}
+
+func (s *source) scrapeDownloadURL(id string) (string, error) {
+	page := fmt.Sprintf("%s/downloads/download%s", s.baseURL, id)
+	doc, err := util.GetHTMLPage(s.client, page)
+	if err != nil {
+		return "", err
+	}
+
+	link, available := doc.Find(".manuallink > a").Attr("href")
+	if !available {
+		return "", fmt.Errorf("failed to find download link for: %s", page)
+	}
+
+	return link, nil
+}
+
 func (s *source) Close() error {
 	return s.downloader.Close()
 }
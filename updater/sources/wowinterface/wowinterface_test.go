@@ -1,15 +1,18 @@
 package wowinterface
 
 import (
+	"crypto/md5"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/unly/wow-addon-updater/updater"
 	"github.com/unly/wow-addon-updater/util/tests/helpers"
 )
 
@@ -76,8 +79,36 @@ func Test_GetURLRegex_WoWinterface(t *testing.T) {
 		},
 		{
 			addonURL: "https://www.wowinterface.com/downloads/infoabc.html",
+			want:     false,
+		},
+		{
+			addonURL: "https://www.wowinterface.com/downloads/info25118.html",
+			want:     true,
+		},
+		{
+			addonURL: "https://www.wowinterface.com/downloads/fileinfo.php?id=25118",
+			want:     true,
+		},
+		{
+			addonURL: "https://www.wowinterface.com/downloads/getfile.php?id=25118&aid=1234",
+			want:     true,
+		},
+		{
+			addonURL: "https://www.wowinterface.com/downloads/download25118-DejaClassicStats",
+			want:     true,
+		},
+		{
+			addonURL: "25118",
 			want:     true,
 		},
+		{
+			addonURL: "wowi:25118",
+			want:     true,
+		},
+		{
+			addonURL: "25118-DejaClassicStats",
+			want:     false,
+		},
 		{
 			addonURL: "https://www.wowinterface.com/downloads/info25118-DejaClassicStats",
 			want:     false,
@@ -137,16 +168,18 @@ func Test_GetLatestVersion_WoWInterface(t *testing.T) {
 	tests := []func() *getlatestVersionTest{
 		func() *getlatestVersionTest {
 			mux := http.NewServeMux()
-			mux.HandleFunc("/addon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/info1.html", func(rw http.ResponseWriter, r *http.Request) {
 				_, _ = rw.Write([]byte(getWoWInterfacePage("1.2.3", "")))
 			})
 			server := httptest.NewServer(mux)
 			s := newWoWInterfaceSource(t, nil)
+			s.baseURL = server.URL
+			s.apiURL = server.URL
 
 			return &getlatestVersionTest{
 				name:          "example addon",
 				source:        s,
-				addonURL:      server.URL + "/addon",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				want:          "1.2.3",
 				errorExpected: false,
 				teardown: func() {
@@ -157,16 +190,18 @@ func Test_GetLatestVersion_WoWInterface(t *testing.T) {
 		},
 		func() *getlatestVersionTest {
 			mux := http.NewServeMux()
-			mux.HandleFunc("/addon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/info1.html", func(rw http.ResponseWriter, r *http.Request) {
 				_, _ = rw.Write([]byte(getWoWInterfacePage("", "")))
 			})
 			server := httptest.NewServer(mux)
 			s := newWoWInterfaceSource(t, nil)
+			s.baseURL = server.URL
+			s.apiURL = server.URL
 
 			return &getlatestVersionTest{
 				name:          "empty version",
 				source:        s,
-				addonURL:      server.URL + "/addon",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				want:          "",
 				errorExpected: false,
 				teardown: func() {
@@ -177,16 +212,18 @@ func Test_GetLatestVersion_WoWInterface(t *testing.T) {
 		},
 		func() *getlatestVersionTest {
 			mux := http.NewServeMux()
-			mux.HandleFunc("/addon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/info1.html", func(rw http.ResponseWriter, r *http.Request) {
 				rw.WriteHeader(http.StatusInternalServerError)
 			})
 			server := httptest.NewServer(mux)
 			s := newWoWInterfaceSource(t, nil)
+			s.baseURL = server.URL
+			s.apiURL = server.URL
 
 			return &getlatestVersionTest{
 				name:          "internal server error",
 				source:        s,
-				addonURL:      server.URL + "/addon",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				want:          "",
 				errorExpected: true,
 				teardown: func() {
@@ -197,16 +234,18 @@ func Test_GetLatestVersion_WoWInterface(t *testing.T) {
 		},
 		func() *getlatestVersionTest {
 			mux := http.NewServeMux()
-			mux.HandleFunc("/addon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/info1.html", func(rw http.ResponseWriter, r *http.Request) {
 				_, _ = rw.Write([]byte(fmt.Sprintf(wowinterfaceAddonPage, "1.2.3", "")))
 			})
 			server := httptest.NewServer(mux)
 			s := newWoWInterfaceSource(t, nil)
+			s.baseURL = server.URL
+			s.apiURL = server.URL
 
 			return &getlatestVersionTest{
 				name:          "invalid response",
 				source:        s,
-				addonURL:      server.URL + "/addon",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				want:          "",
 				errorExpected: true,
 				teardown: func() {
@@ -217,16 +256,18 @@ func Test_GetLatestVersion_WoWInterface(t *testing.T) {
 		},
 		func() *getlatestVersionTest {
 			mux := http.NewServeMux()
-			mux.HandleFunc("/addon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/info1.html", func(rw http.ResponseWriter, r *http.Request) {
 				_, _ = rw.Write([]byte(fmt.Sprintf(wowinterfaceAddonPageNoVersion, "")))
 			})
 			server := httptest.NewServer(mux)
 			s := newWoWInterfaceSource(t, nil)
+			s.baseURL = server.URL
+			s.apiURL = server.URL
 
 			return &getlatestVersionTest{
 				name:          "no version",
 				source:        s,
-				addonURL:      server.URL + "/addon",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				want:          "",
 				errorExpected: true,
 				teardown: func() {
@@ -274,13 +315,14 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 				assert.NoError(t, err)
 				_, _ = w.Write(content)
 			})
-			mux.HandleFunc("/downloads/downloadaddon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/download1", func(rw http.ResponseWriter, r *http.Request) {
 				assert.Equal(t, http.MethodGet, r.Method)
 				_, _ = rw.Write([]byte(fmt.Sprintf(wowinterfaceDownloadPage, server.URL+"/download/addon")))
 			})
 			dir := helpers.TempDir(t)
 			source := newWoWInterfaceSource(t, nil)
 			source.baseURL = server.URL
+			source.apiURL = server.URL
 			teardown := func() {
 				_ = source.Close()
 				server.Close()
@@ -289,7 +331,7 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 			return &downloadAddonTest{
 				name:          "download sample file",
 				source:        source,
-				addonURL:      server.URL + "/infoaddon.html",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				dir:           dir,
 				outputDir:     dir + "/root",
 				errorExpected: false,
@@ -299,13 +341,14 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 		func() *downloadAddonTest {
 			mux := http.NewServeMux()
 			server := httptest.NewServer(mux)
-			mux.HandleFunc("/downloads/downloadaddon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/download1", func(rw http.ResponseWriter, r *http.Request) {
 				assert.Equal(t, http.MethodGet, r.Method)
 				_, _ = rw.Write([]byte("Hello World"))
 			})
 			dir := helpers.TempDir(t)
 			source := newWoWInterfaceSource(t, nil)
 			source.baseURL = server.URL
+			source.apiURL = server.URL
 			teardown := func() {
 				_ = source.Close()
 				server.Close()
@@ -314,7 +357,7 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 			return &downloadAddonTest{
 				name:          "invalid web payload",
 				source:        source,
-				addonURL:      server.URL + "/infoaddon.html",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				dir:           dir,
 				outputDir:     "",
 				errorExpected: true,
@@ -324,13 +367,14 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 		func() *downloadAddonTest {
 			mux := http.NewServeMux()
 			server := httptest.NewServer(mux)
-			mux.HandleFunc("/downloads/downloadaddon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/download1", func(rw http.ResponseWriter, r *http.Request) {
 				assert.Equal(t, http.MethodGet, r.Method)
 				rw.WriteHeader(http.StatusInternalServerError)
 			})
 			dir := helpers.TempDir(t)
 			source := newWoWInterfaceSource(t, nil)
 			source.baseURL = server.URL
+			source.apiURL = server.URL
 			teardown := func() {
 				_ = source.Close()
 				server.Close()
@@ -339,7 +383,7 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 			return &downloadAddonTest{
 				name:          "internal server error",
 				source:        source,
-				addonURL:      server.URL + "/infoaddon.html",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				dir:           dir,
 				outputDir:     "",
 				errorExpected: true,
@@ -349,13 +393,14 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 		func() *downloadAddonTest {
 			mux := http.NewServeMux()
 			server := httptest.NewServer(mux)
-			mux.HandleFunc("/downloads/downloadaddon", func(rw http.ResponseWriter, r *http.Request) {
+			mux.HandleFunc("/downloads/download1", func(rw http.ResponseWriter, r *http.Request) {
 				assert.Equal(t, http.MethodGet, r.Method)
 				_, _ = rw.Write([]byte(fmt.Sprintf(wowinterfaceDownloadPage, "not-existing")))
 			})
 			dir := helpers.TempDir(t)
 			source := newWoWInterfaceSource(t, nil)
 			source.baseURL = server.URL
+			source.apiURL = server.URL
 			teardown := func() {
 				_ = source.Close()
 				server.Close()
@@ -364,7 +409,7 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 			return &downloadAddonTest{
 				name:          "not existing addon",
 				source:        source,
-				addonURL:      server.URL + "/infoaddon.html",
+				addonURL:      "https://www.wowinterface.com/downloads/info1-Addon.html",
 				dir:           dir,
 				outputDir:     "",
 				errorExpected: true,
@@ -388,3 +433,261 @@ func Test_DownloadAddon_WoWInterface(t *testing.T) {
 		})
 	}
 }
+
+func Test_ExpandReference_WoWInterface(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "24608", want: "https://www.wowinterface.com/downloads/info24608.html"},
+		{ref: "24608-Hekili", wantErr: true},
+		{ref: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := source{}.ExpandReference(tt.ref)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.True(t, regex.MatchString(got))
+			}
+		})
+	}
+}
+
+func Test_GetAddonID_WoWInterface(t *testing.T) {
+	tests := []struct {
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{addonURL: "https://www.wowinterface.com/downloads/info24608-Hekili.html", want: "wowi:24608"},
+		{addonURL: "http://wowinterface.com/downloads/info24608-HekiliPriorityHelper.html", want: "wowi:24608"},
+		{addonURL: "https://www.wowinterface.com/downloads/info24608.html", want: "wowi:24608"},
+		{addonURL: "www.wowinterface.com/downloads/fileinfo.php?s=abc&id=24608", want: "wowi:24608"},
+		{addonURL: "https://www.wowinterface.com/downloads/getfile.php?id=24608&aid=1234", want: "wowi:24608/1234"},
+		{addonURL: "https://www.wowinterface.com/downloads/getfile.php?id=24608&aid=abc", want: "wowi:24608"},
+		{addonURL: "https://www.wowinterface.com/downloads/download24608-Hekili", want: "wowi:24608"},
+		{addonURL: "https://www.wowinterface.com/downloads/download24608/", want: "wowi:24608"},
+		{addonURL: " wowi:24608 ", want: "wowi:24608"},
+		{addonURL: " 24608 ", want: "wowi:24608"},
+		{addonURL: "wowi:abc", wantErr: true},
+		{addonURL: "https://www.wowinterface.com/downloads/infoHekili.html", wantErr: true},
+		{addonURL: "https://www.wowinterface.com/downloads/info.html", wantErr: true},
+		{addonURL: "https://www.wowinterface.com/downloads/fileinfo.php?id=abc", wantErr: true},
+		{addonURL: "https://www.wowinterface.com/downloads/fileinfo.php", wantErr: true},
+		{addonURL: "https://www.wowinterface.com/forums/showthread.php?t=24608", wantErr: true},
+		{addonURL: "https://example.com/downloads/info24608-Hekili.html", wantErr: true},
+		{addonURL: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addonURL, func(t *testing.T) {
+			got, err := source{}.GetAddonID(tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+// newAPIServer returns a test server answering the API request of the addon 24608 with the given
+// file details as well as the addon and download pages as fallback. {{server}} is replaced with the server URL.
+func newAPIServer(t *testing.T, details string, requests *int) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filedetails/24608.json", func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if details == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(strings.ReplaceAll(details, "{{server}}", server.URL)))
+	})
+	mux.HandleFunc("/downloads/info24608.html", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(getWoWInterfacePage("0.9", "")))
+	})
+	mux.HandleFunc("/downloads/download24608", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(fmt.Sprintf(wowinterfaceDownloadPage, server.URL+"/files/hekili.zip")))
+	})
+	mux.HandleFunc("/files/hekili.zip", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join("..", "_tests", "archive1.zip"))
+	})
+	server = httptest.NewServer(mux)
+
+	return server
+}
+
+func Test_API_WoWInterface(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("..", "_tests", "archive1.zip"))
+	if err != nil {
+		assert.FailNow(t, "failed to read archive", err)
+	}
+	details := `[{"UID": "24608", "UIName": "Hekili", "UIVersion": "1.2.3", "UIMD5": "%x", "UIDownload": "{{server}}/files/hekili.zip",
+		"UIChangeLog": "changes", "UICompatibility": [{"version": "9.1.5", "name": "Chains of Domination"}]}]`
+
+	tests := []struct {
+		name         string
+		details      string
+		wantVersion  string
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "api details",
+			details:      fmt.Sprintf(details, md5.Sum(content)),
+			wantVersion:  "1.2.3",
+			wantRequests: 1,
+		},
+		{
+			name:         "checksum mismatch",
+			details:      fmt.Sprintf(details, md5.Sum([]byte{})),
+			wantVersion:  "1.2.3",
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "empty api response",
+			details:      `[]`,
+			wantVersion:  "0.9",
+			wantRequests: 2,
+		},
+		{
+			name:         "empty api version",
+			details:      `[{"UID": "24608", "UIVersion": "", "UIDownload": "{{server}}/files/hekili.zip"}]`,
+			wantVersion:  "0.9",
+			wantRequests: 2,
+		},
+		{
+			name:         "api not available",
+			wantVersion:  "0.9",
+			wantRequests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := newAPIServer(t, tt.details, &requests)
+			defer server.Close()
+			s := newWoWInterfaceSource(t, nil)
+			defer s.Close()
+			s.apiURL = server.URL
+			s.baseURL = server.URL
+			addonURL := "https://www.wowinterface.com/downloads/info24608-Hekili.html"
+			dir := helpers.TempDir(t)
+			defer helpers.DeleteDir(t, dir)()
+
+			version, err := s.GetLatestVersion(addonURL)
+			assert.NoError(t, err)
+			err = s.DownloadAddon(addonURL, dir)
+
+			assert.Equal(t, tt.wantVersion, version)
+			assert.Equal(t, tt.wantRequests, requests)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.FileExists(t, filepath.Join(dir, "root", "a.txt"))
+			}
+		})
+	}
+}
+
+const wowinterfaceOptionalFilesPage = `
+<!DOCTYPE html>
+<html>
+<body>
+	<div id="version">Version: 1.0</div>
+	<div id="other_files">
+		<table>
+			<tr><td>Name</td><td>Version</td><td>Date</td></tr>
+			<tr><td><a href="getfile.php?id=24608&amp;aid=111">Hekili Classic</a></td><td>1.0.2</td><td>06-01-21</td></tr>
+			<tr><td><a href="/downloads/getfile.php?id=24608&amp;aid=222">Localization</a></td><td>2.0</td><td>06-02-21</td></tr>
+		</table>
+		<a href="getfile.php?id=24608&amp;aid=444">Unlisted</a>
+	</div>
+</body>
+</html>
+`
+
+func Test_OptionalFiles_WoWInterface(t *testing.T) {
+	mux := http.NewServeMux()
+	pageRequests := 0
+	mux.HandleFunc("/downloads/info24608.html", func(w http.ResponseWriter, r *http.Request) {
+		pageRequests++
+		_, _ = w.Write([]byte(wowinterfaceOptionalFilesPage))
+	})
+	mux.HandleFunc("/downloads/getfile.php", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "24608", r.URL.Query().Get("id"))
+		assert.Equal(t, "111", r.URL.Query().Get("aid"))
+		http.ServeFile(w, r, filepath.Join("..", "_tests", "archive1.zip"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	s := newWoWInterfaceSource(t, nil)
+	defer s.Close()
+	s.baseURL = server.URL
+	addonURL := "https://www.wowinterface.com/downloads/info24608-Hekili.html"
+	classicURL := "https://www.wowinterface.com/downloads/getfile.php?id=24608&aid=111"
+
+	t.Run("file urls", func(t *testing.T) {
+		urls, err := s.GetFileURLs(addonURL, []string{updater.MainFile, "hekili classic", "222"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			addonURL,
+			classicURL,
+			"https://www.wowinterface.com/downloads/getfile.php?id=24608&aid=222",
+		}, urls)
+	})
+	t.Run("unknown file", func(t *testing.T) {
+		_, err := s.GetFileURLs(addonURL, []string{"Retail"})
+
+		assert.Error(t, err)
+	})
+	t.Run("addon id", func(t *testing.T) {
+		id, err := s.GetAddonID(classicURL)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "wowi:24608/111", id)
+	})
+	t.Run("version", func(t *testing.T) {
+		version, err := s.GetLatestVersion(classicURL)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "1.0.2 06-01-21", version)
+	})
+	t.Run("unknown file version", func(t *testing.T) {
+		_, err := s.GetLatestVersion("https://www.wowinterface.com/downloads/getfile.php?id=24608&aid=333")
+
+		assert.Error(t, err)
+	})
+	t.Run("file without version", func(t *testing.T) {
+		_, err := s.GetLatestVersion("https://www.wowinterface.com/downloads/getfile.php?id=24608&aid=444")
+
+		assert.Error(t, err)
+	})
+	t.Run("page fetched once", func(t *testing.T) {
+		assert.Equal(t, 1, pageRequests)
+	})
+	t.Run("download", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+
+		err := s.DownloadAddon(classicURL, dir)
+
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "root", "a.txt"))
+	})
+}
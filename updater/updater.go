@@ -1,15 +1,20 @@
 package updater
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"regexp"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
+	"github.com/unly/wow-addon-updater/updater/sources"
 	"github.com/unly/wow-addon-updater/util"
 )
 
@@ -18,13 +23,20 @@ import (
 type Updater struct {
 	classic     gameUpdater
 	retail      gameUpdater
-	sources     []UpdateSource
+	sources     *Registry
 	versionFile string
 }
 
 type gameUpdater struct {
-	config   config.WowConfig
-	versions map[string]addon
+	config config.WowConfig
+	flavor game.Flavor
+	// game interface version of the installation or 0 if unknown
+	interfaceVersion int
+	versions         map[string]addon
+	// URLs of the addons other addons depend on by their lower case folder name
+	dependencies map[string]string
+	// problems of the addons reported at the end of the run
+	summary []string
 }
 
 //go:generate go run github.com/vektra/mockery/v2 --case=underscore  --name=UpdateSource --structname=MockUpdateSource
@@ -42,8 +54,57 @@ type UpdateSource interface {
 	DownloadAddon(addonURL, dir string) error
 }
 
+// ReferenceExpander can be implemented by an UpdateSource to support short references
+// to addons in the config, e.g. gh:owner/repo instead of https://github.com/owner/repo
+type ReferenceExpander interface {
+	// ReferencePrefix returns the prefix of the short references without the colon, e.g. gh
+	ReferencePrefix() string
+	// ExpandReference returns the addon URL for the short reference without its prefix
+	ExpandReference(ref string) (string, error)
+}
+
+// Identifier can be implemented by an UpdateSource to track addons independent of the
+// spelling of their URL, e.g. with or without www. or a trailing slash
+type Identifier interface {
+	// GetAddonID returns the canonical ID of the addon of the given URL, e.g. github.com/owner/repo.
+	// It is unique across all sources and does not depend on the name of the source.
+	GetAddonID(addonURL string) (string, error)
+}
+
+// FlavorSource can be implemented by an UpdateSource serving different builds
+// of an addon for the game flavors
+type FlavorSource interface {
+	// ForFlavor returns the source to use for the addons of the given flavor
+	ForFlavor(flavor game.Flavor) UpdateSource
+}
+
+// ExternalSource can be implemented by an UpdateSource applying the .pkgmeta files of the
+// addons, whose externals may be hosted elsewhere
+type ExternalSource interface {
+	// FetchExternal downloads the external of the given URL at the optional tag to dest.
+	// Returns sources.ErrUnsupportedExternal for an external the source does not host.
+	FetchExternal(url, tag, dest string) error
+	// SetExternalFetcher sets the fetcher for all externals of the addons of the source
+	SetExternalFetcher(fetch sources.ExternalFetcher)
+}
+
+// MainFile is the name of the main file of an addon offering several files
+const MainFile = "main"
+
+// FileSource can be implemented by an UpdateSource offering several files for an addon,
+// e.g. optional modules or localizations
+type FileSource interface {
+	// GetFileURLs returns the URLs of the given files of the addon. Each file is
+	// updated and tracked on its own. The MainFile is the main file of the addon.
+	GetFileURLs(addonURL string, files []string) ([]string, error)
+}
+
+// addon is an entry of the versions file. The name is the canonical key of the addon
+// whereas the URL is the one of the config. Entries of previous releases without a URL
+// are named by the addon URL.
 type addon struct {
 	Name    string `yaml:"name"`
+	URL     string `yaml:"url,omitempty"`
 	Version string `yaml:"version"`
 }
 
@@ -54,8 +115,8 @@ type versions struct {
 
 // NewUpdater returns a pointer to a newly created Updater or an error if it fails to read in
 // the version tracking file.
-// Uses the config.Config to identify the addons
-func NewUpdater(config config.Config, sources []UpdateSource, versionFile string) (*Updater, error) {
+// Uses the config.Config to identify the addons and warns about addons matched by multiple sources
+func NewUpdater(conf config.Config, sources *Registry, versionFile string) (*Updater, error) {
 	if !util.IsHiddenFilePath(versionFile) {
 		return nil, fmt.Errorf("the version file path %s can not be used for a hidden file", versionFile)
 	}
@@ -66,30 +127,60 @@ func NewUpdater(config config.Config, sources []UpdateSource, versionFile string
 	}
 
 	if sources == nil {
-		sources = make([]UpdateSource, 0)
+		sources = NewRegistry()
+	}
+
+	conf.Classic.AddOns, err = expandAddons(sources, conf.Classic.AddOns)
+	if err != nil {
+		return nil, err
+	}
+	conf.Retail.AddOns, err = expandAddons(sources, conf.Retail.AddOns)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Updater{
+	dependencies, err := getDependencies(sources, conf.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	classicFlavor := getFlavor(conf.Classic, game.Classic)
+	retailFlavor := getFlavor(conf.Retail, game.Retail)
+	u := &Updater{
 		classic: gameUpdater{
-			config:   config.Classic,
-			versions: mapAddonVersions(readVersions.Classic),
+			config:           conf.Classic,
+			flavor:           classicFlavor,
+			interfaceVersion: getInterface(conf.Classic, classicFlavor),
+			versions:         mapAddonVersions(readVersions.Classic),
+			dependencies:     dependencies,
 		},
 		retail: gameUpdater{
-			config:   config.Retail,
-			versions: mapAddonVersions(readVersions.Retail),
+			config:           conf.Retail,
+			flavor:           retailFlavor,
+			interfaceVersion: getInterface(conf.Retail, retailFlavor),
+			versions:         mapAddonVersions(readVersions.Retail),
+			dependencies:     dependencies,
 		},
 		sources:     sources,
 		versionFile: versionFile,
-	}, nil
+	}
+	u.retail.warnAmbiguousAddons(sources)
+	u.classic.warnAmbiguousAddons(sources)
+	u.retail.migrateVersions(sources)
+	u.classic.migrateVersions(sources)
+
+	return u, nil
 }
 
-// UpdateAddons updates all the addons given in the configuration
+// UpdateAddons updates all the addons given in the configuration.
+// Logs a summary of the problems of the addons at the end.
 func (u *Updater) UpdateAddons() error {
 	defer func() {
 		if err := saveVersionsFile(u); err != nil {
 			log.Printf("failed to write versions file: %v", err)
 		}
 	}()
+	defer u.logSummary()
 
 	err := u.retail.updateAddons(u.sources)
 	if err != nil {
@@ -103,14 +194,78 @@ func (u *Updater) UpdateAddons() error {
 	return nil
 }
 
-func (g *gameUpdater) updateAddons(sources []UpdateSource) error {
-	for _, addon := range g.config.AddOns {
-		source, err := getSource(sources, addon)
+// Summary returns the problems of the addons found during the run
+func (u *Updater) Summary() []string {
+	return append(append([]string{}, u.retail.summary...), u.classic.summary...)
+}
+
+func (u *Updater) logSummary() {
+	summary := u.Summary()
+	if len(summary) == 0 {
+		return
+	}
+
+	log.Printf("summary: %d problem(s) found\n", len(summary))
+	for _, problem := range summary {
+		log.Printf("  %s\n", problem)
+	}
+}
+
+// updateAddons updates the configured addons and installs the missing dependencies of the
+// installed addons. Dependencies are installed before the addons if they are already known.
+func (g *gameUpdater) updateAddons(sources *Registry) error {
+	attempted := make(map[string]bool)
+	err := g.updateInstalledDependencies(sources, attempted)
+	if err != nil {
+		return err
+	}
+	err = g.installDependencies(sources, attempted)
+	if err != nil {
+		return err
+	}
+
+	for _, addon := range g.orderAddons() {
+		err = g.updateConfiguredAddon(sources, addon)
 		if err != nil {
 			return err
 		}
+	}
+
+	err = g.installDependencies(sources, attempted)
+	if err != nil {
+		return err
+	}
+
+	return g.reportMissingDependencies()
+}
 
-		err = g.updateAddon(addon, source)
+// updateConfiguredAddon updates the files of the addon with its source
+func (g *gameUpdater) updateConfiguredAddon(sources *Registry, addon config.AddOn) error {
+	name, source, err := getSource(sources, addon)
+	if err != nil {
+		return err
+	}
+	if flavorSource, ok := source.(FlavorSource); ok {
+		source = flavorSource.ForFlavor(g.flavor)
+	}
+
+	urls, err := getFileURLs(name, source, addon)
+	if err != nil {
+		return err
+	}
+
+	for _, url := range urls {
+		key, err := addonKey(source, url)
+		if err != nil {
+			return err
+		}
+
+		if version := g.getCurrentVersion(key); addon.Pinned && version != "" {
+			log.Printf("addon %s is pinned to version %s\n", url, version)
+			continue
+		}
+
+		err = g.updateAddon(key, url, source)
 		if err != nil {
 			return err
 		}
@@ -119,8 +274,22 @@ func (g *gameUpdater) updateAddons(sources []UpdateSource) error {
 	return nil
 }
 
-func (g *gameUpdater) getCurrentVersion(addonURL string) string {
-	add, ok := g.versions[addonURL]
+// getFileURLs returns the URLs of the selected files of the addon or just the addon URL
+func getFileURLs(sourceName string, source UpdateSource, addon config.AddOn) ([]string, error) {
+	if len(addon.Files) == 0 {
+		return []string{addon.URL}, nil
+	}
+
+	fileSource, ok := source.(FileSource)
+	if !ok {
+		return nil, fmt.Errorf("the source %s of addon %s does not support selecting files", sourceName, addon.URL)
+	}
+
+	return fileSource.GetFileURLs(addon.URL, addon.Files)
+}
+
+func (g *gameUpdater) getCurrentVersion(key string) string {
+	add, ok := g.versions[key]
 	if !ok {
 		return ""
 	}
@@ -128,26 +297,27 @@ func (g *gameUpdater) getCurrentVersion(addonURL string) string {
 	return add.Version
 }
 
-func (g *gameUpdater) setCurrentVersion(addonURL, version string) {
+func (g *gameUpdater) setCurrentVersion(key, addonURL, version string) {
 	if g.versions == nil {
 		g.versions = make(map[string]addon)
 	}
 
-	add, ok := g.versions[addonURL]
+	add, ok := g.versions[key]
 	if !ok {
 		add = addon{
-			Name: addonURL,
+			Name: key,
 		}
 	}
 
+	add.URL = addonURL
 	add.Version = version
-	g.versions[addonURL] = add
+	g.versions[key] = add
 }
 
-func (g *gameUpdater) updateAddon(addonURL string, source UpdateSource) error {
+func (g *gameUpdater) updateAddon(key, addonURL string, source UpdateSource) error {
 	log.Printf("updating addon: %s\n", addonURL)
 
-	currentVersion := g.getCurrentVersion(addonURL)
+	currentVersion := g.getCurrentVersion(key)
 
 	latestVersion, err := source.GetLatestVersion(addonURL)
 	if err != nil {
@@ -159,24 +329,185 @@ func (g *gameUpdater) updateAddon(addonURL string, source UpdateSource) error {
 		return nil
 	}
 
-	err = source.DownloadAddon(addonURL, g.config.Path)
-	if err != nil {
-		return err
+	if g.checksCompatibility() {
+		installed, err := g.installChecked(addonURL, source)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			log.Printf("skipped version %s\n", latestVersion)
+			return nil
+		}
+	} else {
+		err = g.downloadAddon(addonURL, g.config.Path, source)
+		if err != nil {
+			return err
+		}
 	}
 
-	g.setCurrentVersion(addonURL, latestVersion)
+	g.setCurrentVersion(key, addonURL, latestVersion)
 	log.Printf("updated to version: %s\n", latestVersion)
 	return nil
 }
 
-func getSource(sources []UpdateSource, addonURL string) (UpdateSource, error) {
-	for _, source := range sources {
-		if source.GetURLRegex().MatchString(addonURL) {
-			return source, nil
+// downloadAddon downloads the addon to the given directory. The externals of the addon
+// no source supports are reported, as the addon is installed without them.
+func (g *gameUpdater) downloadAddon(addonURL, dir string, source UpdateSource) error {
+	err := source.DownloadAddon(addonURL, dir)
+
+	var missing *sources.MissingExternalsError
+	if !errors.As(err, &missing) {
+		return err
+	}
+	for _, url := range missing.URLs {
+		g.report(addonURL, fmt.Sprintf("installed without the unsupported external %s", url))
+	}
+
+	return nil
+}
+
+func (g *gameUpdater) warnAmbiguousAddons(sources *Registry) {
+	for _, addon := range g.config.AddOns {
+		if addon.Source != "" {
+			continue
+		}
+
+		names := sources.Matching(addon.URL)
+		if len(names) > 1 {
+			log.Printf("warning: addon url %s is matched by the sources %s. using %s, set the source of the addon to change it\n",
+				addon.URL, strings.Join(names, ", "), names[0])
+		}
+	}
+}
+
+// getFlavor returns the configured flavor of the installation or the default one of its section
+func getFlavor(conf config.WowConfig, section game.Flavor) game.Flavor {
+	if conf.Flavor != "" {
+		return conf.Flavor
+	}
+
+	return section
+}
+
+// expandAddons returns a copy of the addons with short references expanded to their URLs
+func expandAddons(sources *Registry, addons []config.AddOn) ([]config.AddOn, error) {
+	if addons == nil {
+		return nil, nil
+	}
+
+	expanded := make([]config.AddOn, len(addons))
+	for i, addon := range addons {
+		url, err := sources.Expand(addon.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		addon.URL = url
+		expanded[i] = addon
+	}
+
+	return expanded, nil
+}
+
+// migrateVersions moves the entries tracked by the addon URL in previous releases or by an
+// outdated key to the canonical key of the addon. An entry already tracked by its key wins over
+// moved ones. Entries of unsupported URLs are kept as they are.
+func (g *gameUpdater) migrateVersions(sources *Registry) {
+	names := make([]string, 0, len(g.versions))
+	for name := range g.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	migrated := make(map[string]addon, len(g.versions))
+	for _, name := range names {
+		add := g.versions[name]
+		url := add.URL
+		if url == "" {
+			url = name
 		}
+
+		key := name
+		configured := config.AddOn{URL: url}
+		for _, a := range g.config.AddOns {
+			if a.URL == url {
+				configured = a
+				break
+			}
+		}
+		if _, source, err := getSource(sources, configured); err == nil {
+			if id, err := addonKey(source, url); err == nil {
+				key = id
+				add.URL = url
+			}
+		}
+
+		if _, ok := migrated[key]; ok && name != key {
+			continue
+		}
+		add.Name = key
+		migrated[key] = add
+	}
+
+	g.versions = migrated
+}
+
+// getSource returns the name and the source of the addon. That is the source set in the config
+// or the first one matching the addon URL.
+func getSource(sources *Registry, addon config.AddOn) (string, UpdateSource, error) {
+	if addon.Source == "" {
+		return sources.Find(addon.URL)
+	}
+
+	source, ok := sources.Get(addon.Source)
+	if !ok {
+		return "", nil, fmt.Errorf("source %s of addon %s does not exist", addon.Source, addon.URL)
+	}
+
+	return addon.Source, source, nil
+}
+
+// CheckAddOn returns an error if the addon of the config is not supported by the sources.
+// That is its short reference cannot be expanded, no source handles its URL, the source
+// cannot identify the addon or does not offer the configured files.
+func CheckAddOn(sources *Registry, addon config.AddOn) error {
+	url, err := sources.Expand(addon.URL)
+	if err != nil {
+		return err
+	}
+	addon.URL = url
+
+	name, source, err := getSource(sources, addon)
+	if err != nil {
+		return err
+	}
+
+	_, err = addonKey(source, addon.URL)
+	if err != nil {
+		return fmt.Errorf("source %s cannot identify the addon: %v", name, err)
+	}
+
+	if _, ok := source.(FileSource); len(addon.Files) > 0 && !ok {
+		return fmt.Errorf("source %s does not offer several files of an addon", name)
+	}
+
+	return nil
+}
+
+// addonKey returns the key of the addon in the versions file. It is the canonical ID
+// of the addon if the source is an Identifier, otherwise the addon URL.
+func addonKey(source UpdateSource, addonURL string) (string, error) {
+	identifier, ok := source.(Identifier)
+	if !ok {
+		return addonURL, nil
+	}
+
+	id, err := identifier.GetAddonID(addonURL)
+	if err != nil {
+		return "", err
 	}
 
-	return nil, fmt.Errorf("addon url: %s is not supported", addonURL)
+	return id, nil
 }
 
 func readVersionsFile(path string) (versions, error) {
@@ -5,13 +5,16 @@ import (
 	"errors"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 
 	"github.com/unly/wow-addon-updater/config"
+	"github.com/unly/wow-addon-updater/game"
 	"github.com/unly/wow-addon-updater/updater/mocks"
+	"github.com/unly/wow-addon-updater/updater/sources"
 	"github.com/unly/wow-addon-updater/util"
 	"github.com/unly/wow-addon-updater/util/tests/helpers"
 )
@@ -431,18 +434,19 @@ func Test_setCurrentVersion(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		tt.updater.setCurrentVersion(tt.addon, tt.version)
+		tt.updater.setCurrentVersion(tt.addon, "example.com/"+tt.addon, tt.version)
 
 		actual, ok := tt.updater.versions[tt.addon]
 		assert.True(t, ok)
 		assert.Equal(t, tt.version, actual.Version)
+		assert.Equal(t, "example.com/"+tt.addon, actual.URL)
 	}
 }
 
 func Test_NewUpdater(t *testing.T) {
 	type newUpdaterTest struct {
 		config        config.Config
-		sources       []UpdateSource
+		sources       *Registry
 		versionFile   string
 		errorExpected bool
 		want          *Updater
@@ -458,12 +462,16 @@ func Test_NewUpdater(t *testing.T) {
 				errorExpected: false,
 				want: &Updater{
 					classic: gameUpdater{
-						versions: map[string]addon{},
+						flavor:       game.Classic,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
 					},
 					retail: gameUpdater{
-						versions: map[string]addon{},
+						flavor:       game.Retail,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
 					},
-					sources:     []UpdateSource{},
+					sources:     NewRegistry(),
 					versionFile: ".file",
 				},
 				teardown: helpers.NoopTeardown(),
@@ -473,15 +481,15 @@ func Test_NewUpdater(t *testing.T) {
 			c := config.Config{
 				Classic: config.WowConfig{
 					Path: "path/to/addons/dir",
-					AddOns: []string{
-						"addon1",
-						"addon2",
+					AddOns: []config.AddOn{
+						{URL: "addon1"},
+						{URL: "addon2"},
 					},
 				},
 			}
-			sources := []UpdateSource{
-				&mocks.MockUpdateSource{},
-			}
+			m := &mocks.MockUpdateSource{}
+			m.On("GetURLRegex").Return(regexp.MustCompile("addon.+"))
+			sources := newRegistry(t, m)
 
 			return &newUpdaterTest{
 				config:        c,
@@ -490,11 +498,15 @@ func Test_NewUpdater(t *testing.T) {
 				errorExpected: false,
 				want: &Updater{
 					classic: gameUpdater{
-						config:   c.Classic,
-						versions: map[string]addon{},
+						flavor:       game.Classic,
+						config:       c.Classic,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
 					},
 					retail: gameUpdater{
-						versions: map[string]addon{},
+						flavor:       game.Retail,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
 					},
 					sources:     sources,
 					versionFile: ".file",
@@ -523,52 +535,142 @@ func Test_NewUpdater(t *testing.T) {
 			c := config.Config{
 				Classic: config.WowConfig{
 					Path: "path/to/addons/dir",
-					AddOns: []string{
-						"addon1",
-						"addon2",
+					AddOns: []config.AddOn{
+						{URL: "addon1"},
+						{URL: "addon2"},
 					},
 				},
 				Retail: config.WowConfig{
 					Path: "path/to/retail/addons/dir",
-					AddOns: []string{
-						"addon3",
-						"addon4",
+					AddOns: []config.AddOn{
+						{URL: "addon3"},
+						{URL: "addon4"},
 					},
 				},
 			}
 
 			return &newUpdaterTest{
 				config:        c,
-				sources:       []UpdateSource{},
+				sources:       NewRegistry(),
 				versionFile:   file,
 				errorExpected: false,
 				want: &Updater{
 					classic: gameUpdater{
-						config:   c.Classic,
-						versions: map[string]addon{},
+						flavor:       game.Classic,
+						config:       c.Classic,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
 					},
 					retail: gameUpdater{
-						config:   c.Retail,
-						versions: map[string]addon{},
+						flavor:       game.Retail,
+						config:       c.Retail,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
 					},
-					sources:     []UpdateSource{},
+					sources:     NewRegistry(),
 					versionFile: file,
 				},
 				teardown: helpers.DeleteFile(t, file),
 			}
 		},
+		func() *newUpdaterTest {
+			c := config.Config{
+				Classic: config.WowConfig{
+					Path:   "path/to/addons/dir",
+					Flavor: game.ClassicTBC,
+				},
+			}
+
+			return &newUpdaterTest{
+				config:        c,
+				sources:       NewRegistry(),
+				versionFile:   ".file",
+				errorExpected: false,
+				want: &Updater{
+					classic: gameUpdater{
+						config:       c.Classic,
+						flavor:       game.ClassicTBC,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
+					},
+					retail: gameUpdater{
+						flavor:       game.Retail,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
+					},
+					sources:     NewRegistry(),
+					versionFile: ".file",
+				},
+				teardown: helpers.NoopTeardown(),
+			}
+		},
 		func() *newUpdaterTest {
 			file := helpers.TempFile(t, "", []byte("just text"))
 
 			return &newUpdaterTest{
 				config:        config.Config{},
-				sources:       []UpdateSource{},
+				sources:       NewRegistry(),
 				versionFile:   file,
 				errorExpected: true,
 				want:          nil,
 				teardown:      helpers.DeleteFile(t, file),
 			}
 		},
+		func() *newUpdaterTest {
+			sources := newRegistry(t, expander{mockSource("example.com/.+")})
+			c := config.Config{
+				Retail: config.WowConfig{
+					Path: "path/to/retail/addons/dir",
+					AddOns: []config.AddOn{
+						{URL: "ex:addon"},
+					},
+				},
+			}
+
+			return &newUpdaterTest{
+				config:        c,
+				sources:       sources,
+				versionFile:   ".file",
+				errorExpected: false,
+				want: &Updater{
+					classic: gameUpdater{
+						flavor:       game.Classic,
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
+					},
+					retail: gameUpdater{
+						flavor: game.Retail,
+						config: config.WowConfig{
+							Path: "path/to/retail/addons/dir",
+							AddOns: []config.AddOn{
+								{URL: "example.com/addon"},
+							},
+						},
+						versions:     map[string]addon{},
+						dependencies: knownDependencies,
+					},
+					sources:     sources,
+					versionFile: ".file",
+				},
+				teardown: helpers.NoopTeardown(),
+			}
+		},
+		func() *newUpdaterTest {
+			return &newUpdaterTest{
+				config: config.Config{
+					Classic: config.WowConfig{
+						AddOns: []config.AddOn{
+							{URL: "ex:"},
+						},
+					},
+				},
+				sources:       newRegistry(t, expander{mockSource("example.com/.+")}),
+				versionFile:   ".file",
+				errorExpected: true,
+				want:          nil,
+				teardown:      helpers.NoopTeardown(),
+			}
+		},
 	}
 
 	for _, fn := range tests {
@@ -623,12 +725,12 @@ func Test_UpdateAddons(t *testing.T) {
 				updater: &Updater{
 					classic: gameUpdater{
 						config: config.WowConfig{
-							AddOns: []string{
-								"addon",
+							AddOns: []config.AddOn{
+								{URL: "addon"},
 							},
 						},
 					},
-					sources:     []UpdateSource{},
+					sources:     NewRegistry(),
 					versionFile: file,
 				},
 				errorExpected:     true,
@@ -643,12 +745,12 @@ func Test_UpdateAddons(t *testing.T) {
 				updater: &Updater{
 					retail: gameUpdater{
 						config: config.WowConfig{
-							AddOns: []string{
-								"addon",
+							AddOns: []config.AddOn{
+								{URL: "addon"},
 							},
 						},
 					},
-					sources:     []UpdateSource{},
+					sources:     NewRegistry(),
 					versionFile: file,
 				},
 				errorExpected:     true,
@@ -680,8 +782,8 @@ func Test_UpdateAddons(t *testing.T) {
 
 func Test_getSource(t *testing.T) {
 	type getSourceTest struct {
-		sources       []UpdateSource
-		addonURL      string
+		sources       *Registry
+		addon         config.AddOn
 		errorExpected bool
 		want          UpdateSource
 	}
@@ -689,8 +791,8 @@ func Test_getSource(t *testing.T) {
 	tests := []func() *getSourceTest{
 		func() *getSourceTest {
 			return &getSourceTest{
-				sources:       nil,
-				addonURL:      "example.com",
+				sources:       NewRegistry(),
+				addon:         config.AddOn{URL: "example.com"},
 				errorExpected: true,
 				want:          nil,
 			}
@@ -700,10 +802,8 @@ func Test_getSource(t *testing.T) {
 			m.On("GetURLRegex").Return(regexp.MustCompile("test.com/.+"))
 
 			return &getSourceTest{
-				sources: []UpdateSource{
-					&m,
-				},
-				addonURL:      "example.com",
+				sources:       newRegistry(t, &m),
+				addon:         config.AddOn{URL: "example.com"},
 				errorExpected: true,
 				want:          nil,
 			}
@@ -715,21 +815,39 @@ func Test_getSource(t *testing.T) {
 			m2.On("GetURLRegex").Return(regexp.MustCompile("example.com/.+"))
 
 			return &getSourceTest{
-				sources: []UpdateSource{
-					&m1,
-					&m2,
-				},
-				addonURL:      "example.com/addon",
+				sources:       newRegistry(t, &m1, &m2),
+				addon:         config.AddOn{URL: "example.com/addon"},
 				errorExpected: false,
 				want:          &m2,
 			}
 		},
+		func() *getSourceTest {
+			m1 := mocks.MockUpdateSource{}
+			m2 := mocks.MockUpdateSource{}
+
+			return &getSourceTest{
+				sources:       newRegistry(t, &m1, &m2),
+				addon:         config.AddOn{URL: "example.com/addon", Source: "source0"},
+				errorExpected: false,
+				want:          &m1,
+			}
+		},
+		func() *getSourceTest {
+			m := mocks.MockUpdateSource{}
+
+			return &getSourceTest{
+				sources:       newRegistry(t, &m),
+				addon:         config.AddOn{URL: "example.com/addon", Source: "unknown"},
+				errorExpected: true,
+				want:          nil,
+			}
+		},
 	}
 
 	for _, fn := range tests {
 		tt := fn()
 
-		actual, err := getSource(tt.sources, tt.addonURL)
+		_, actual, err := getSource(tt.sources, tt.addon)
 
 		if tt.errorExpected {
 			assert.Error(t, err)
@@ -847,7 +965,7 @@ func Test_updateAddon(t *testing.T) {
 	for _, fn := range tests {
 		tt := fn()
 
-		err := tt.updater.updateAddon(tt.addonURL, tt.source)
+		err := tt.updater.updateAddon(tt.addonURL, tt.addonURL, tt.source)
 
 		if tt.errorExpected {
 			assert.Error(t, err)
@@ -863,10 +981,28 @@ func Test_updateAddon(t *testing.T) {
 	}
 }
 
+func Test_updateAddon_MissingExternals(t *testing.T) {
+	url := "example.com/addon"
+	g := &gameUpdater{
+		config: config.WowConfig{
+			Path: "addon/dir",
+		},
+	}
+	m := mocks.MockUpdateSource{}
+	m.On("GetLatestVersion", url).Return("1.2.3", nil)
+	m.On("DownloadAddon", url, g.config.Path).Return(&sources.MissingExternalsError{URLs: []string{"svn://example.com/lib"}})
+
+	err := g.updateAddon(url, url, &m)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3", g.getCurrentVersion(url))
+	assert.Equal(t, []string{"example.com/addon: installed without the unsupported external svn://example.com/lib"}, g.summary)
+}
+
 func Test_updateAddons(t *testing.T) {
 	type updateAddons struct {
 		updater       *gameUpdater
-		sources       []UpdateSource
+		sources       *Registry
 		errorExpected bool
 	}
 
@@ -874,7 +1010,7 @@ func Test_updateAddons(t *testing.T) {
 		func() *updateAddons {
 			return &updateAddons{
 				updater:       &gameUpdater{},
-				sources:       nil,
+				sources:       NewRegistry(),
 				errorExpected: false,
 			}
 		},
@@ -882,12 +1018,12 @@ func Test_updateAddons(t *testing.T) {
 			return &updateAddons{
 				updater: &gameUpdater{
 					config: config.WowConfig{
-						AddOns: []string{
-							"example.com/addon",
+						AddOns: []config.AddOn{
+							{URL: "example.com/addon"},
 						},
 					},
 				},
-				sources:       nil,
+				sources:       NewRegistry(),
 				errorExpected: true,
 			}
 		},
@@ -901,14 +1037,12 @@ func Test_updateAddons(t *testing.T) {
 			return &updateAddons{
 				updater: &gameUpdater{
 					config: config.WowConfig{
-						AddOns: []string{
-							url,
+						AddOns: []config.AddOn{
+							{URL: url},
 						},
 					},
 				},
-				sources: []UpdateSource{
-					&m,
-				},
+				sources:       newRegistry(t, &m),
 				errorExpected: false,
 			}
 		},
@@ -921,14 +1055,12 @@ func Test_updateAddons(t *testing.T) {
 			return &updateAddons{
 				updater: &gameUpdater{
 					config: config.WowConfig{
-						AddOns: []string{
-							url,
+						AddOns: []config.AddOn{
+							{URL: url},
 						},
 					},
 				},
-				sources: []UpdateSource{
-					&m,
-				},
+				sources:       newRegistry(t, &m),
 				errorExpected: true,
 			}
 		},
@@ -946,3 +1078,268 @@ func Test_updateAddons(t *testing.T) {
 		}
 	}
 }
+
+// identifier is a source identifying addons by the last path element of the URL
+type identifier struct {
+	*mocks.MockUpdateSource
+}
+
+func (identifier) GetAddonID(addonURL string) (string, error) {
+	split := strings.Split(strings.TrimSuffix(addonURL, "/"), "/")
+	if len(split) < 2 {
+		return "", errors.New("no addon id")
+	}
+
+	return strings.ToLower(split[len(split)-1]), nil
+}
+
+func Test_addonKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   UpdateSource
+		addonURL string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "addon url",
+			source:   &mocks.MockUpdateSource{},
+			addonURL: "example.com/addon",
+			want:     "example.com/addon",
+		},
+		{
+			name:     "canonical id",
+			source:   identifier{&mocks.MockUpdateSource{}},
+			addonURL: "https://www.example.com/Addon/",
+			want:     "addon",
+		},
+		{
+			name:     "invalid url",
+			source:   identifier{&mocks.MockUpdateSource{}},
+			addonURL: "addon",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addonKey(tt.source, tt.addonURL)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckAddOn(t *testing.T) {
+	sources := newRegistry(t,
+		expander{mockSource(`^example\.com/.+`)},
+		identifier{mockSource(`^id\.com`)},
+		multiFile{mockSource(`^files\.com/.+`)},
+	)
+
+	tests := []struct {
+		name    string
+		addon   config.AddOn
+		wantErr bool
+	}{
+		{name: "supported url", addon: config.AddOn{URL: "example.com/addon"}},
+		{name: "short reference", addon: config.AddOn{URL: "ex:addon"}},
+		{name: "invalid reference", addon: config.AddOn{URL: "ex:"}, wantErr: true},
+		{name: "unsupported url", addon: config.AddOn{URL: "other.com/addon"}, wantErr: true},
+		{name: "configured source", addon: config.AddOn{URL: "other.com/addon", Source: "source0"}},
+		{name: "missing source", addon: config.AddOn{URL: "example.com/addon", Source: "missing"}, wantErr: true},
+		{name: "no addon id", addon: config.AddOn{URL: "id.com"}, wantErr: true},
+		{name: "files", addon: config.AddOn{URL: "files.com/addon", Files: []string{MainFile}}},
+		{name: "files not offered", addon: config.AddOn{URL: "example.com/addon", Files: []string{MainFile}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAddOn(sources, tt.addon)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_migrateVersions(t *testing.T) {
+	sources := newRegistry(t, identifier{mockSource("example.com/.+")}, mockSource("other.com/.+"))
+	g := &gameUpdater{
+		config: config.WowConfig{
+			AddOns: []config.AddOn{
+				{URL: "https://example.com/addon"},
+			},
+		},
+		versions: map[string]addon{
+			"https://example.com/addon": {
+				Name:    "https://example.com/addon",
+				Version: "1.2.3",
+			},
+			"http://www.example.com/Addon2/": {
+				Name:    "http://www.example.com/Addon2/",
+				Version: "2.0",
+			},
+			"other.com/addon": {
+				Name:    "other.com/addon",
+				Version: "3.0",
+			},
+			"unsupported.com/addon": {
+				Name:    "unsupported.com/addon",
+				Version: "4.0",
+			},
+			"source0:addon3": {
+				Name:    "source0:addon3",
+				URL:     "example.com/addon3",
+				Version: "5.0",
+			},
+			"example.com/addon4": {
+				Name:    "example.com/addon4",
+				Version: "6.0",
+			},
+			"addon4": {
+				Name:    "addon4",
+				URL:     "https://example.com/addon4",
+				Version: "6.1",
+			},
+		},
+	}
+
+	g.migrateVersions(sources)
+
+	want := map[string]addon{
+		"addon": {
+			Name:    "addon",
+			URL:     "https://example.com/addon",
+			Version: "1.2.3",
+		},
+		"addon2": {
+			Name:    "addon2",
+			URL:     "http://www.example.com/Addon2/",
+			Version: "2.0",
+		},
+		"other.com/addon": {
+			Name:    "other.com/addon",
+			URL:     "other.com/addon",
+			Version: "3.0",
+		},
+		"unsupported.com/addon": {
+			Name:    "unsupported.com/addon",
+			Version: "4.0",
+		},
+		"addon3": {
+			Name:    "addon3",
+			URL:     "example.com/addon3",
+			Version: "5.0",
+		},
+		"addon4": {
+			Name:    "addon4",
+			URL:     "https://example.com/addon4",
+			Version: "6.1",
+		},
+	}
+	assert.Equal(t, want, g.versions)
+}
+
+// flavored is a source recording the flavor it is used for
+type flavored struct {
+	*mocks.MockUpdateSource
+	flavor *game.Flavor
+}
+
+func (f flavored) ForFlavor(flavor game.Flavor) UpdateSource {
+	*f.flavor = flavor
+	return f
+}
+
+func Test_updateAddons_Flavor(t *testing.T) {
+	url := "example.com/addon"
+	m := mockSource("example.com/.+")
+	m.On("GetLatestVersion", url).Return("1.2.3", nil)
+	m.On("DownloadAddon", url, "").Return(nil)
+	var flavor game.Flavor
+	g := &gameUpdater{
+		config: config.WowConfig{
+			AddOns: []config.AddOn{{URL: url}},
+		},
+		flavor: game.ClassicTBC,
+	}
+
+	err := g.updateAddons(newRegistry(t, flavored{m, &flavor}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, game.ClassicTBC, flavor)
+}
+
+// multiFile is a source offering the files of an addon as addon URL and file name
+type multiFile struct {
+	*mocks.MockUpdateSource
+}
+
+func (multiFile) GetFileURLs(addonURL string, files []string) ([]string, error) {
+	urls := make([]string, len(files))
+	for i, file := range files {
+		urls[i] = addonURL + "#" + file
+	}
+
+	return urls, nil
+}
+
+func Test_updateAddons_Files(t *testing.T) {
+	url := "example.com/addon"
+	m := mockSource("example.com/.+")
+	for _, file := range []string{MainFile, "classic"} {
+		m.On("GetLatestVersion", url+"#"+file).Return("1.2.3", nil)
+		m.On("DownloadAddon", url+"#"+file, "").Return(nil)
+	}
+	g := &gameUpdater{
+		config: config.WowConfig{
+			AddOns: []config.AddOn{{URL: url, Files: []string{MainFile, "classic"}}},
+		},
+	}
+
+	t.Run("files of the addon", func(t *testing.T) {
+		err := g.updateAddons(newRegistry(t, multiFile{m}))
+
+		assert.NoError(t, err)
+		assert.Len(t, g.versions, 2)
+		assert.Equal(t, "1.2.3", g.getCurrentVersion(url+"#classic"))
+		m.AssertNumberOfCalls(t, "DownloadAddon", 2)
+	})
+	t.Run("source without files", func(t *testing.T) {
+		err := g.updateAddons(newRegistry(t, m))
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_updateAddons_Pinned(t *testing.T) {
+	m := mockSource("example.com/.+")
+	m.On("GetLatestVersion", "example.com/new").Return("1.2.3", nil)
+	m.On("DownloadAddon", "example.com/new", "").Return(nil)
+	g := &gameUpdater{
+		config: config.WowConfig{
+			AddOns: []config.AddOn{
+				{URL: "example.com/installed", Pinned: true},
+				{URL: "example.com/new", Pinned: true},
+			},
+		},
+		versions: map[string]addon{
+			"example.com/installed": {Name: "example.com/installed", Version: "1.0.0"},
+		},
+	}
+
+	err := g.updateAddons(newRegistry(t, m))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", g.getCurrentVersion("example.com/installed"))
+	assert.Equal(t, "1.2.3", g.getCurrentVersion("example.com/new"))
+	m.AssertNumberOfCalls(t, "GetLatestVersion", 1)
+}
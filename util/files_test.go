@@ -206,3 +206,71 @@ func TestUnzip(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestCopyDir(t *testing.T) {
+	t.Run("not existing src", func(t *testing.T) {
+		err := CopyDir("not-existing", "dst")
+
+		assert.Error(t, err)
+	})
+	t.Run("file src", func(t *testing.T) {
+		file := helpers.TempFile(t, "", []byte{})
+		defer helpers.DeleteFile(t, file)()
+
+		err := CopyDir(file, "dst")
+
+		assert.Error(t, err)
+	})
+	t.Run("replace existing dst", func(t *testing.T) {
+		src := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, src)()
+		dst := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dst)()
+		assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), os.ModePerm))
+		assert.NoError(t, os.WriteFile(filepath.Join(src, "sub", "a.txt"), []byte("a"), os.FileMode(0666)))
+		assert.NoError(t, os.WriteFile(filepath.Join(dst, "stale.txt"), []byte{}, os.FileMode(0666)))
+
+		err := CopyDir(src, dst)
+
+		assert.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(dst, "sub", "a.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("a"), content)
+		assert.NoFileExists(t, filepath.Join(dst, "stale.txt"))
+	})
+}
+
+func TestHashFile(t *testing.T) {
+	t.Run("not existing file", func(t *testing.T) {
+		_, err := HashFile("not-existing")
+
+		assert.Error(t, err)
+	})
+	t.Run("file content", func(t *testing.T) {
+		file := helpers.TempFile(t, "", []byte("hello world"))
+		defer helpers.DeleteFile(t, file)()
+
+		got, err := HashFile(file)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", got)
+	})
+}
+
+func TestHashDir(t *testing.T) {
+	dir := helpers.TempDir(t)
+	defer helpers.DeleteDir(t, dir)()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), os.FileMode(0666)))
+
+	first, err := HashDir(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("b"), os.FileMode(0666)))
+	second, err := HashDir(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Rename(filepath.Join(dir, "a.txt"), filepath.Join(dir, "c.txt")))
+	third, err := HashDir(dir)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.NotEqual(t, second, third)
+}
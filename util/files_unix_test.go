@@ -102,3 +102,27 @@ func TestWriteToHiddenFile_Unix(t *testing.T) {
 	err := WriteToHiddenFile("dir/test.file", []byte("hello world"), os.FileMode(0666))
 	assert.Error(t, err, "WriteToHiddenFile() returned no error")
 }
+
+func TestCopyFile(t *testing.T) {
+	t.Run("keep file mode", func(t *testing.T) {
+		dir := helpers.TempDir(t)
+		defer helpers.DeleteDir(t, dir)()
+		src := filepath.Join(dir, "script.sh")
+		err := os.WriteFile(src, []byte("echo"), os.FileMode(0750))
+		if err != nil {
+			assert.FailNow(t, "failed to write to test file", err)
+		}
+		err = os.Chmod(src, os.FileMode(0750))
+		if err != nil {
+			assert.FailNow(t, "failed to set the file mode", err)
+		}
+		dst := filepath.Join(dir, "copy.sh")
+
+		err = CopyFile(src, dst)
+
+		assert.NoError(t, err)
+		info, err := os.Stat(dst)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+	})
+}
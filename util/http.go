@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -39,3 +40,16 @@ func GetHTMLPage(client *http.Client, url string) (*goquery.Document, error) {
 
 	return goquery.NewDocumentFromReader(resp.Body)
 }
+
+// GetJSON gets the given url and decodes the JSON response body into v
+// or returns an error if the HTTP call or the decoding failed.
+func GetJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	err = CheckHTTPResponse(resp, err)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -130,3 +130,39 @@ func TestGetHTMLPage(t *testing.T) {
 		assert.NotNil(t, doc)
 	})
 }
+
+func TestGetJSON(t *testing.T) {
+	t.Run("failed http call", func(t *testing.T) {
+		var v map[string]string
+		err := GetJSON(new(http.Client), "no url", &v)
+
+		assert.Error(t, err)
+	})
+	t.Run("invalid json", func(t *testing.T) {
+		m := http.NewServeMux()
+		m.HandleFunc("/", func(rw http.ResponseWriter, _ *http.Request) {
+			_, _ = rw.Write([]byte("<html></html>"))
+		})
+		s := httptest.NewServer(m)
+		defer s.Close()
+
+		var v map[string]string
+		err := GetJSON(new(http.Client), s.URL, &v)
+
+		assert.Error(t, err)
+	})
+	t.Run("example document", func(t *testing.T) {
+		m := http.NewServeMux()
+		m.HandleFunc("/", func(rw http.ResponseWriter, _ *http.Request) {
+			_, _ = rw.Write([]byte(`{"version": "1.2.3"}`))
+		})
+		s := httptest.NewServer(m)
+		defer s.Close()
+
+		var v map[string]string
+		err := GetJSON(new(http.Client), s.URL, &v)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"version": "1.2.3"}, v)
+	})
+}